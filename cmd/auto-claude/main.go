@@ -4,8 +4,10 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	tea "charm.land/bubbletea/v2"
@@ -15,7 +17,9 @@ import (
 	"github.com/marcin-skalski/auto-claude/internal/git"
 	"github.com/marcin-skalski/auto-claude/internal/github"
 	"github.com/marcin-skalski/auto-claude/internal/logging"
+	"github.com/marcin-skalski/auto-claude/internal/state"
 	"github.com/marcin-skalski/auto-claude/internal/tui"
+	"github.com/marcin-skalski/auto-claude/internal/worker"
 	"github.com/mattn/go-isatty"
 )
 
@@ -40,11 +44,22 @@ func main() {
 		os.Exit(1)
 	}
 
-	gh := github.NewClient(logger)
+	gh, gitTokens, err := newGitHubClients(cfg, logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
 	cl := claude.NewClient(cfg.Claude.Model, logger)
-	g := git.NewClient(cfg.Workdir, logger)
+	g := git.NewClient(cfg.Workdir, gitTokens, logger)
+
+	st, err := state.Open(cfg.StateFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
 
-	d := daemon.New(cfg, gh, cl, g, logger)
+	d := daemon.New(cfg, gh, cl, g, st, logger)
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
@@ -83,3 +98,76 @@ func main() {
 		}
 	}
 }
+
+// newGitHubClients builds one client per host, so each tracks its own rate
+// limits. With app auth it's one per host and owner instead, since every
+// installation has its own token and quota; the returned TokenFunc then hands
+// the installation tokens to git, and is nil otherwise.
+func newGitHubClients(cfg *config.Config, logger *slog.Logger) (func(config.RepoConfig) worker.GitHub, git.TokenFunc, error) {
+	app := cfg.GitHub.App
+	clientKey := func(host, owner string) string {
+		if app == nil {
+			return host
+		}
+		return host + "/" + strings.ToLower(owner)
+	}
+
+	clients := make(map[string]*github.Client)           // key: clientKey
+	installations := make(map[string]github.TokenSource) // key: clientKey
+	appAuths := make(map[string]*github.AppAuth)         // key: host
+	for _, repo := range cfg.Repos {
+		key := clientKey(repo.Host, repo.Owner)
+		if _, ok := clients[key]; ok {
+			continue
+		}
+
+		var tokens github.TokenSource
+		if app != nil {
+			auth, ok := appAuths[repo.Host]
+			if !ok {
+				var err error
+				auth, err = github.NewAppAuth(app.ID, app.PrivateKeyPath, cfg.GitHub.ForHost(repo.Host).APIURL, logger)
+				if err != nil {
+					return nil, nil, err
+				}
+				appAuths[repo.Host] = auth
+			}
+			tokens = auth.Installation(repo.Owner, repo.Name)
+			installations[key] = tokens
+		}
+
+		transport, err := newTransport(cfg.GitHub, repo.Host, tokens, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		clients[key] = github.NewClient(transport, logger)
+	}
+
+	gh := func(repo config.RepoConfig) worker.GitHub {
+		return clients[clientKey(repo.Host, repo.Owner)]
+	}
+	if app == nil {
+		return gh, nil, nil
+	}
+	gitTokens := func(ctx context.Context, host, owner string) (string, error) {
+		tokens, ok := installations[clientKey(host, owner)]
+		if !ok {
+			return "", fmt.Errorf("no app installation for %s on %s", owner, host)
+		}
+		return tokens.Token(ctx)
+	}
+	return gh, gitTokens, nil
+}
+
+// newTransport creates the configured backend for host. A nil tokens uses the
+// backend's own credentials: gh's login, or the token_env variable.
+func newTransport(cfg config.GitHubConfig, host string, tokens github.TokenSource, logger *slog.Logger) (github.Transport, error) {
+	if cfg.Backend == "api" {
+		hc := cfg.ForHost(host)
+		if tokens == nil {
+			tokens = github.EnvToken(hc.TokenEnv)
+		}
+		return github.NewHTTPTransport(hc.APIURL, tokens, nil)
+	}
+	return github.NewGHTransport(host, tokens, logger), nil
+}
@@ -0,0 +1,68 @@
+package ci
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Failure categories. Infrastructure failures (timeout, dependency download,
+// runner lost) usually pass on a re-run; the others need a code change.
+const (
+	CompileError       = "compile_error"
+	TestFailure        = "test_failure"
+	Lint               = "lint"
+	Timeout            = "timeout"
+	DependencyDownload = "dependency_download"
+	RunnerLost         = "runner_lost"
+	Unknown            = "unknown"
+)
+
+// Failure is what is known about a failed check.
+type Failure struct {
+	Check       string   // check name
+	TimedOut    bool     // the check concluded timed_out
+	Annotations []string // annotation titles and messages
+	Log         string   // job log, empty when unavailable
+}
+
+// excerptBudget is how much of a job log is classified: the trimmed error
+// excerpt, not the whole log, which is full of ordinary output.
+const excerptBudget = 8 << 10
+
+var (
+	runnerLostRe = regexp.MustCompile(`(?im)runner has received a shutdown signal|lost communication with the server|^the runner .* (did not connect|lost)|no space left on device|^(##\[error\])?the operation was canceled\.?$`)
+	timeoutRe    = regexp.MustCompile(`(?i)has exceeded the maximum execution time|exceeded the maximum execution time of \d+ minutes`)
+	dependencyRe = regexp.MustCompile(`(?im)could not resolve host|temporary failure in name resolution|^go: .*(dial tcp|tls handshake timeout|i/o timeout|connection reset by peer|unexpected status code 5\d\d|: 50[234] )|^npm err! (network|code e(connreset|timedout|ai_again))|toomanyrequests: |error pulling image|^\s*(error: )?failed to download\b|^##\[error\].*(connection reset by peer|econnreset|etimedout)`)
+	lintNameRe   = regexp.MustCompile(`(?i)(^|[-_ /:(.])(lint|fmt|format|vet|style|check-?types)|eslint|gofmt`)
+	lintLogRe    = regexp.MustCompile(`golangci-lint|eslint|prettier|gofmt|goimports|ruff|flake8|rubocop|would reformat`)
+	compileRe    = regexp.MustCompile(`(?m)\[build failed\]|^# \S+$|undefined: |cannot use .* as |syntax error|error TS\d+|error\[E\d+\]|cannot find symbol|compilation (failed|terminated)`)
+	testRe       = regexp.MustCompile(`(?m)^\s*--- FAIL|^FAIL\b|AssertionError|Tests? failed|\d+ failed|FAILED`)
+)
+
+// Classify sorts a failed check into a category, from its annotations and
+// the error excerpt of its log. Lost runners and job timeouts win, since the
+// log then ends mid-run. Compile and test failures come before the other
+// infrastructure causes: ordinary failing test output mentions refused
+// connections and timeouts too. A lint job is recognized by its name before
+// its output.
+func Classify(f Failure) string {
+	text := strings.Join(f.Annotations, "\n") + "\n" + TrimLog(f.Log, excerptBudget)
+	switch {
+	case runnerLostRe.MatchString(text):
+		return RunnerLost
+	case f.TimedOut || timeoutRe.MatchString(text):
+		return Timeout
+	case lintNameRe.MatchString(f.Check):
+		return Lint
+	case compileRe.MatchString(text):
+		return CompileError
+	case testRe.MatchString(text):
+		return TestFailure
+	case dependencyRe.MatchString(text):
+		return DependencyDownload
+	case lintLogRe.MatchString(text):
+		return Lint
+	default:
+		return Unknown
+	}
+}
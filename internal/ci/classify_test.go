@@ -0,0 +1,130 @@
+package ci
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		f    Failure
+		want string
+	}{
+		{
+			name: "go test failure mentioning a refused connection",
+			f: Failure{Check: "test", Log: strings.Join([]string{
+				"=== RUN   TestDB",
+				"    db_test.go:12: dial tcp 127.0.0.1:5432: connect: connection refused",
+				"--- FAIL: TestDB (0.01s)",
+				"FAIL",
+				"FAIL\texample.com/db\t0.02s",
+			}, "\n")},
+			want: TestFailure,
+		},
+		{
+			name: "test failure mentioning a timeout and a 502",
+			f: Failure{Check: "test", Log: strings.Join([]string{
+				"    client_test.go:40: request timed out after 5s",
+				"    client_test.go:41: got status: 502 Bad Gateway",
+				"    client_test.go:42: failed to download fixture",
+				"--- FAIL: TestClient (5.00s)",
+				"FAIL",
+			}, "\n")},
+			want: TestFailure,
+		},
+		{
+			name: "compile error",
+			f:    Failure{Check: "build", Log: "# example.com/pkg\npkg/a.go:3:2: undefined: Foo\n"},
+			want: CompileError,
+		},
+		{
+			name: "lint job by name",
+			f:    Failure{Check: "golangci-lint", Log: "--- FAIL: nothing to see\n"},
+			want: Lint,
+		},
+		{
+			name: "lint job in a workflow",
+			f:    Failure{Check: "CI / lint (ubuntu-latest)", Log: "exit status 1\n"},
+			want: Lint,
+		},
+		{
+			name: "eslint job",
+			f:    Failure{Check: "eslint", Log: "exit status 1\n"},
+			want: Lint,
+		},
+		{
+			name: "lint inside a word isn't a lint job",
+			f:    Failure{Check: "splinter", Log: "something went wrong\n"},
+			want: Unknown,
+		},
+		{
+			name: "format inside a word isn't a lint job",
+			f:    Failure{Check: "Information gathering", Log: "something went wrong\n"},
+			want: Unknown,
+		},
+		{
+			name: "lint findings in log",
+			f:    Failure{Check: "checks", Log: "running golangci-lint\nmain.go:1:1: exported func should have comment\n"},
+			want: Lint,
+		},
+		{
+			name: "module download failure",
+			f:    Failure{Check: "build", Log: "go: downloading example.com/dep v1.0.0\ngo: example.com/dep@v1.0.0: Get \"https://proxy.golang.org/...\": dial tcp: i/o timeout\n##[error]Process completed with exit code 1.\n"},
+			want: DependencyDownload,
+		},
+		{
+			name: "npm network error",
+			f:    Failure{Check: "build", Log: "npm ERR! code ECONNRESET\nnpm ERR! network aborted\n"},
+			want: DependencyDownload,
+		},
+		{
+			name: "runner lost",
+			f:    Failure{Check: "test", Annotations: []string{"The runner has received a shutdown signal."}},
+			want: RunnerLost,
+		},
+		{
+			name: "cancelled job",
+			f:    Failure{Check: "test", Log: "2024-01-01T00:00:00.0000000Z ##[error]The operation was canceled.\n"},
+			want: RunnerLost,
+		},
+		{
+			name: "timed out conclusion",
+			f:    Failure{Check: "test", TimedOut: true, Log: "--- FAIL: TestSlow\n"},
+			want: Timeout,
+		},
+		{
+			name: "job exceeded the maximum execution time",
+			f:    Failure{Check: "test", Annotations: []string{"The job running on runner X has exceeded the maximum execution time of 360 minutes."}},
+			want: Timeout,
+		},
+		{
+			name: "test failure deep in a long log isn't masked by earlier noise",
+			f: Failure{Check: "test", Log: strings.Repeat("go: downloading example.com/dep v1.0.0\n", 10) +
+				strings.Repeat("ok  \texample.com/pkg\t0.01s\n", 2000) +
+				"--- FAIL: TestX (0.00s)\nFAIL\n"},
+			want: TestFailure,
+		},
+		{
+			name: "unknown",
+			f:    Failure{Check: "deploy", Log: "something went wrong\n"},
+			want: Unknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.f); got != tt.want {
+				t.Errorf("Classify() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyIgnoresInfraNoiseOutsideExcerpt(t *testing.T) {
+	// A download failure early in a long log, retried successfully, doesn't
+	// make the failure at its end an infrastructure one
+	log := "go: example.com/dep@v1.0.0: dial tcp: i/o timeout\n" + strings.Repeat("noise\n", 5000) + "##[error]Process completed with exit code 2.\n"
+	if got := Classify(Failure{Check: "e2e", Log: log}); got != Unknown {
+		t.Errorf("Classify() = %q, want %q", got, Unknown)
+	}
+}
@@ -0,0 +1,126 @@
+// Package ci extracts what matters from CI job logs before they are handed
+// to Claude.
+package ci
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	contextBefore = 30 // lines kept above an error line
+	contextAfter  = 10 // lines kept below an error line
+	tailLines     = 20 // lines always kept from the end, where the job's exit status is
+)
+
+var (
+	// Actions prefixes every line with an RFC 3339 timestamp
+	timestampRe = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?Z ?`)
+	ansiRe      = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+	errorLineRe = regexp.MustCompile(`##\[error\]|^\s*--- FAIL|^FAIL\b|^panic:|(?i)\berror\b[:\[]|\bfatal\b:|Traceback \(most recent call last\)`)
+)
+
+type lineRange struct{ start, end int } // [start, end)
+
+// TrimLog cuts a CI job log down to at most budget bytes, keeping the lines
+// around errors (latest first) and the end of the log. Timestamps and ANSI
+// colors are stripped, and gaps are marked with the number of lines omitted.
+func TrimLog(log string, budget int) string {
+	lines := strings.Split(strings.TrimRight(log, "\n"), "\n")
+	for i, line := range lines {
+		line = timestampRe.ReplaceAllString(line, "")
+		lines[i] = ansiRe.ReplaceAllString(strings.TrimRight(line, "\r"), "")
+	}
+
+	size := func(r lineRange) int {
+		n := 0
+		for _, line := range lines[r.start:r.end] {
+			n += len(line) + 1
+		}
+		return n
+	}
+
+	if size(lineRange{0, len(lines)}) <= budget {
+		return strings.Join(lines, "\n")
+	}
+
+	// Candidate windows in order of importance: the tail, then errors from
+	// the last one backwards, since later errors are usually the cause.
+	candidates := []lineRange{{max(0, len(lines)-tailLines), len(lines)}}
+	for i := len(lines) - 1; i >= 0; i-- {
+		if errorLineRe.MatchString(lines[i]) {
+			candidates = append(candidates, lineRange{max(0, i-contextBefore), min(len(lines), i+contextAfter+1)})
+		}
+	}
+
+	var kept []lineRange
+	used := 0
+	for _, c := range candidates {
+		// Overlapping windows are counted in full, so this errs under budget
+		n := size(c)
+		if used+n > budget {
+			if len(kept) == 0 {
+				// Even the tail doesn't fit: keep as many final lines as do
+				for c.start < c.end-1 && size(c) > budget {
+					c.start++
+				}
+				if size(c) > budget {
+					// Not even the last line: keep its end
+					lines[c.start] = lastBytes(lines[c.start], budget-1)
+				}
+				kept = append(kept, c)
+			}
+			continue
+		}
+		kept = append(kept, c)
+		used += n
+	}
+
+	return render(lines, merge(kept))
+}
+
+// lastBytes returns the end of s, at most n bytes long, without splitting a
+// UTF-8 sequence.
+func lastBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	i := len(s) - max(0, n)
+	for i < len(s) && !utf8.RuneStart(s[i]) {
+		i++
+	}
+	return s[i:]
+}
+
+// merge sorts ranges and joins overlapping or adjacent ones.
+func merge(ranges []lineRange) []lineRange {
+	slices.SortFunc(ranges, func(a, b lineRange) int { return a.start - b.start })
+	var merged []lineRange
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && r.start <= merged[n-1].end {
+			merged[n-1].end = max(merged[n-1].end, r.end)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+func render(lines []string, ranges []lineRange) string {
+	var b strings.Builder
+	next := 0
+	for _, r := range ranges {
+		if r.start > next {
+			fmt.Fprintf(&b, "... (%d lines omitted) ...\n", r.start-next)
+		}
+		for _, line := range lines[r.start:r.end] {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+		next = r.end
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,66 @@
+package ci
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTrimLog(t *testing.T) {
+	tests := []struct {
+		name   string
+		log    string
+		budget int
+		want   string
+	}{
+		{
+			name:   "short log is kept whole",
+			log:    "a\nb\nc\n",
+			budget: 100,
+			want:   "a\nb\nc",
+		},
+		{
+			name:   "timestamps and colors are stripped",
+			log:    "2024-01-01T00:00:00.0000000Z \x1b[31mred\x1b[0m\r\n2024-01-01T00:00:01Z plain\n",
+			budget: 100,
+			want:   "red\nplain",
+		},
+		{
+			name:   "log exactly at the budget is kept whole",
+			log:    "abc\ndef\n",
+			budget: 8,
+			want:   "abc\ndef",
+		},
+		{
+			name:   "log one byte over the budget drops the first line",
+			log:    "abc\ndef\n",
+			budget: 7,
+			want:   "... (1 lines omitted) ...\ndef",
+		},
+		{
+			name:   "last line alone over the budget keeps its end",
+			log:    "first\n" + strings.Repeat("x", 50) + "END\n",
+			budget: 11,
+			want:   "... (1 lines omitted) ...\nxxxxxxxEND",
+		},
+		{
+			name:   "truncated last line doesn't split a character",
+			log:    "éééé\n",
+			budget: 4,
+			want:   "é",
+		},
+		{
+			name:   "error context is kept besides the tail",
+			log:    "noise\n" + "error: boom\n" + strings.Repeat("ok\n", 50) + "done\n",
+			budget: 120,
+			want:   "noise\nerror: boom\n" + strings.Repeat("ok\n", 10) + "... (21 lines omitted) ...\n" + strings.Repeat("ok\n", 19) + "done",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TrimLog(tt.log, tt.budget)
+			if got != tt.want {
+				t.Errorf("TrimLog() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,12 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
-	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 	"sync"
-	"time"
 )
 
 type Client struct {
@@ -26,7 +23,6 @@ func NewClient(model string, logger *slog.Logger) *Client {
 type Result struct {
 	Success      bool
 	Output       string
-	OutputFile   string
 	DurationMs   int
 	TotalCostUSD float64
 	SessionID    string
@@ -296,202 +292,3 @@ func extractTextFromStreamEvent(line string) string {
 
 	return ""
 }
-
-// RunCommand spawns Claude Code CLI with a slash command.
-// outputDir specifies where to save full Claude output logs.
-func (c *Client) RunCommand(ctx context.Context, workdir, outputDir, command string, args ...string) (*Result, error) {
-	return c.RunCommandWithCallback(ctx, workdir, outputDir, command, nil, args...)
-}
-
-// RunCommandWithCallback spawns Claude command with live output streaming
-func (c *Client) RunCommandWithCallback(ctx context.Context, workdir, outputDir, command string, callback OutputCallback, args ...string) (*Result, error) {
-	var cliArgs []string
-	if callback != nil {
-		// Use stream-json for real-time streaming
-		cliArgs = []string{
-			"-p", fmt.Sprintf("/%s %s", command, strings.Join(args, " ")),
-			"--output-format", "stream-json",
-			"--verbose",
-			"--include-partial-messages",
-			"--no-session-persistence",
-			"--dangerously-skip-permissions",
-			"--model", c.model,
-		}
-	} else {
-		// Use regular json when no streaming needed
-		cliArgs = []string{
-			"-p", fmt.Sprintf("/%s %s", command, strings.Join(args, " ")),
-			"--output-format", "json",
-			"--no-session-persistence",
-			"--dangerously-skip-permissions",
-			"--model", c.model,
-		}
-	}
-
-	c.logger.Info("spawning claude command", "command", command, "workdir", workdir)
-
-	cmd := exec.CommandContext(ctx, "claude", cliArgs...)
-	cmd.Dir = workdir
-
-	var out []byte
-	var cmdErr error
-
-	if callback == nil {
-		// No streaming
-		out, cmdErr = cmd.CombinedOutput()
-	} else {
-		// Stream output
-		stdout, err := cmd.StdoutPipe()
-		if err != nil {
-			return nil, fmt.Errorf("create stdout pipe: %w", err)
-		}
-		stderr, err := cmd.StderrPipe()
-		if err != nil {
-			return nil, fmt.Errorf("create stderr pipe: %w", err)
-		}
-
-		if err := cmd.Start(); err != nil {
-			return nil, fmt.Errorf("start claude: %w", err)
-		}
-
-		var outputBuf strings.Builder
-		var outputMu sync.Mutex
-		done := make(chan struct{}, 2)
-		scanErrs := make(chan error, 2)
-
-		// Stream stdout (parse stream-json events)
-		go func() {
-			scanner := bufio.NewScanner(stdout)
-			scanner.Buffer(make([]byte, 64*1024), 1024*1024) // 1MB max token
-			var lineBuf strings.Builder // Buffer for partial deltas
-			for scanner.Scan() {
-				line := scanner.Text()
-				outputMu.Lock()
-				outputBuf.WriteString(line)
-				outputBuf.WriteString("\n")
-				outputMu.Unlock()
-
-				// Parse stream-json event and extract text for callback
-				if callback != nil {
-					text := extractTextFromStreamEvent(line)
-					if text != "" {
-						lineBuf.WriteString(text)
-						// Split on newlines and send complete lines
-						content := lineBuf.String()
-						if idx := strings.LastIndex(content, "\n"); idx >= 0 {
-							lines := strings.Split(content[:idx+1], "\n")
-							for _, l := range lines {
-								if l != "" {
-									callback(l)
-								}
-							}
-							lineBuf.Reset()
-							lineBuf.WriteString(content[idx+1:])
-						}
-					}
-				}
-			}
-			// Flush remaining partial line if any
-			if callback != nil && lineBuf.Len() > 0 {
-				callback(lineBuf.String())
-			}
-			scanErrs <- scanner.Err()
-			done <- struct{}{}
-		}()
-
-		// Stream stderr (raw lines)
-		go func() {
-			scanner := bufio.NewScanner(stderr)
-			scanner.Buffer(make([]byte, 64*1024), 1024*1024) // 1MB max token
-			for scanner.Scan() {
-				line := scanner.Text()
-				outputMu.Lock()
-				outputBuf.WriteString(line)
-				outputBuf.WriteString("\n")
-				outputMu.Unlock()
-
-				// Send stderr directly to callback for visibility
-				if callback != nil {
-					callback("[stderr] " + line)
-				}
-			}
-			scanErrs <- scanner.Err()
-			done <- struct{}{}
-		}()
-
-		// Wait for both goroutines
-		<-done
-		<-done
-
-		// Check scanner errors
-		if scanErr := <-scanErrs; scanErr != nil {
-			return nil, fmt.Errorf("scan stdout: %w", scanErr)
-		}
-		if scanErr := <-scanErrs; scanErr != nil {
-			return nil, fmt.Errorf("scan stderr: %w", scanErr)
-		}
-
-		cmdErr = cmd.Wait()
-		outputMu.Lock()
-		out = []byte(outputBuf.String())
-		outputMu.Unlock()
-	}
-
-	// Save full output to file with high-resolution timestamp
-	now := time.Now()
-	timestamp := fmt.Sprintf("%s-%06d", now.Format("20060102-150405"), now.Nanosecond()/1000)
-	logFile := filepath.Join(outputDir, fmt.Sprintf("claude-%s-%s.log", command, timestamp))
-
-	// Create output directory with restrictive permissions
-	if err := os.MkdirAll(outputDir, 0700); err != nil {
-		c.logger.Warn("failed to create output directory", "dir", outputDir, "err", err)
-	}
-
-	// Write log with owner-only permissions
-	if writeErr := os.WriteFile(logFile, out, 0600); writeErr != nil {
-		c.logger.Warn("failed to save claude output", "err", writeErr)
-	}
-
-	if cmdErr != nil {
-		c.logger.Error("claude command failed", "command", command, "output_file", logFile)
-		return &Result{
-			Success:    false,
-			Output:     string(out),
-			OutputFile: logFile,
-		}, fmt.Errorf("claude command %s: %w\n%s", command, cmdErr, string(out))
-	}
-
-	// Try parsing result based on whether streaming was used
-	var result *Result
-	var parseErr error
-
-	if callback != nil {
-		// Stream-json format
-		result, parseErr = c.parseStreamResult(out)
-	} else {
-		// Regular json format
-		result, parseErr = c.parseResult(out)
-	}
-
-	if parseErr != nil {
-		c.logger.Warn("failed to parse result", "err", parseErr)
-		return &Result{
-			Success:    true,
-			Output:     string(out),
-			OutputFile: logFile,
-		}, nil
-	}
-
-	// Add output file to result
-	result.OutputFile = logFile
-
-	c.logger.Info("claude completed",
-		"command", command,
-		"duration_ms", result.DurationMs,
-		"cost_usd", result.TotalCostUSD,
-		"turns", result.NumTurns,
-		"session_id", result.SessionID,
-		"output_file", logFile)
-
-	return result, nil
-}
@@ -3,6 +3,11 @@ package config
 import (
 	"fmt"
 	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -13,25 +18,439 @@ type Config struct {
 	RawInterval  string        `yaml:"poll_interval"`
 	Workdir      string        `yaml:"workdir"`
 	LogFile      string        `yaml:"log_file"`
+	StateFile    string        `yaml:"state_file"` // PR settings made with comment commands
 	Claude       ClaudeConfig  `yaml:"claude"`
+	GitHub       GitHubConfig  `yaml:"github"`
 	Repos        []RepoConfig  `yaml:"repos"`
 	Log          LogConfig     `yaml:"log"`
 	TUI          TUIConfig     `yaml:"tui"`
+	Webhook      WebhookConfig `yaml:"webhook"`
 }
 
 type ClaudeConfig struct {
 	Model string `yaml:"model"`
 }
 
+type GitHubConfig struct {
+	Backend  string                `yaml:"backend"`   // gh | api
+	Host     string                `yaml:"host"`      // default host for repos that don't set one
+	APIURL   string                `yaml:"api_url"`   // REST API root of the default host for the api backend
+	TokenEnv string                `yaml:"token_env"` // env var holding the token for the api backend
+	Hosts    map[string]HostConfig `yaml:"hosts"`     // per-host api backend settings, key: host
+	App      *AppConfig            `yaml:"app,omitempty"`
+}
+
+// AppConfig authenticates as a GitHub App instead of a user. The app must be
+// installed on every configured repo's owner, on the same host.
+type AppConfig struct {
+	ID             int64  `yaml:"id"`
+	PrivateKeyPath string `yaml:"private_key_path"`
+}
+
+// HostConfig overrides api backend settings for one GitHub host.
+type HostConfig struct {
+	APIURL   string `yaml:"api_url"`
+	TokenEnv string `yaml:"token_env"`
+}
+
+// ForHost returns the api backend settings for host. Unset fields fall back
+// to the global ones, with the API URL derived from the host name.
+func (g GitHubConfig) ForHost(host string) HostConfig {
+	hc := g.Hosts[host]
+	if hc.APIURL == "" {
+		if host == g.Host {
+			hc.APIURL = g.APIURL
+		} else {
+			hc.APIURL = apiURL(host)
+		}
+	}
+	if hc.TokenEnv == "" {
+		hc.TokenEnv = g.TokenEnv
+	}
+	return hc
+}
+
+// apiURL is the REST API root of a host: api.github.com for github.com,
+// /api/v3/ on GitHub Enterprise Server.
+func apiURL(host string) string {
+	if host == "github.com" {
+		return "https://api.github.com/"
+	}
+	return "https://" + host + "/api/v3/"
+}
+
 type RepoConfig struct {
-	Owner                 string                 `yaml:"owner"`
-	Name                  string                 `yaml:"name"`
-	BaseBranch            string                 `yaml:"base_branch"`
-	ExcludeAuthors        []string               `yaml:"exclude_authors"`
-	MergeMethod           string                 `yaml:"merge_method"`
-	MaxConcurrentPRs      int                    `yaml:"max_concurrent_prs"`
-	RequireCopilotReview  *bool                  `yaml:"require_copilot_review,omitempty"`
-	ReviewRequestComment  *ReviewRequestComment  `yaml:"review_request_comment,omitempty"`
+	Owner                string                `yaml:"owner"`
+	Name                 string                `yaml:"name"`
+	Host                 string                `yaml:"host"` // e.g. github.com or a GHES hostname
+	BaseBranch           string                `yaml:"base_branch"`
+	BaseBranches         []BaseBranch          `yaml:"base_branches"`
+	ExcludeAuthors       []string              `yaml:"exclude_authors"`
+	MergeMethod          string                `yaml:"merge_method"`
+	MergeStrategy        string                `yaml:"merge_strategy"` // direct | auto_merge | merge_queue
+	MaxConcurrentPRs     int                   `yaml:"max_concurrent_prs"`
+	MaxPRs               int                   `yaml:"max_prs"` // newest open PRs considered per poll; 0 = all
+	RequireCopilotReview *bool                 `yaml:"require_copilot_review,omitempty"`
+	ReviewBots           []ReviewBot           `yaml:"review_bots"` // AI reviewers; Copilot is added unless listed by name
+	ReviewRequestComment *ReviewRequestComment `yaml:"review_request_comment,omitempty"`
+	HumanReviews         *HumanReviewsConfig   `yaml:"human_reviews,omitempty"` // nil: only bot threads are addressed
+	CommitMessage        *CommitMessageConfig  `yaml:"commit_message,omitempty"`
+	CheckConclusions     map[string]string     `yaml:"check_conclusions"`   // conclusion -> action, see CheckAction
+	FixOptionalChecks    bool                  `yaml:"fix_optional_checks"` // also fix and wait for checks branch protection doesn't require
+	CIFailures           map[string]string     `yaml:"ci_failures"`         // failure category -> action, see FailureAction
+	Flaky                FlakyConfig           `yaml:"flaky"`
+	MaxFixAttempts       *int                  `yaml:"max_fix_attempts,omitempty"` // Claude runs on one problem before giving up; 0 = unlimited
+	StatusCheck          *StatusCheckConfig    `yaml:"status_check,omitempty"`     // nil: nothing is published
+	StatusComment        *StatusCommentConfig  `yaml:"status_comment,omitempty"`
+	StateLabels          *StateLabelsConfig    `yaml:"state_labels,omitempty"`
+}
+
+// Status check kinds
+const (
+	StatusCheckRun    = "check_run" // needs GitHub App auth
+	StatusCheckStatus = "status"    // commit status
+)
+
+// StatusCheckConfig publishes what auto-claude is doing on each PR's head
+// commit, as a check run or a commit status.
+type StatusCheckConfig struct {
+	Name             string `yaml:"name"`               // check or status context name
+	Kind             string `yaml:"kind"`               // check_run | status
+	GiveUpConclusion string `yaml:"give_up_conclusion"` // neutral | failure, once auto-claude gave up
+	TranscriptURL    string `yaml:"transcript_url"`     // link to a Claude session; {session_id} is replaced
+}
+
+// StatusCommentConfig keeps one comment per PR up to date with a timeline of
+// auto-claude's states, actions, pushed commits and Claude cost.
+type StatusCommentConfig struct {
+	Enabled    bool `yaml:"enabled"`
+	MaxEntries int  `yaml:"max_entries"` // oldest timeline entries are dropped beyond this, at most MaxStatusCommentEntries
+}
+
+// MaxStatusCommentEntries bounds status_comment.max_entries, keeping the
+// comment well within GitHub's comment size limit.
+const MaxStatusCommentEntries = 100
+
+// StateLabelsConfig mirrors the states auto-claude sees on each PR, as shown
+// in the TUI, onto labels named prefix+state, e.g. auto-claude:checks-failing.
+// Missing labels are created with the configured colour.
+type StateLabelsConfig struct {
+	Enabled bool                  `yaml:"enabled"`
+	Prefix  string                `yaml:"prefix"` // default: "auto-claude:"
+	Labels  map[string]StateLabel `yaml:"labels"` // key: state, see DefaultStateLabelColors; overrides the defaults
+}
+
+// StateLabel is the label of one state.
+type StateLabel struct {
+	Name        string `yaml:"name"`  // default: prefix+state
+	Color       string `yaml:"color"` // hex without '#'
+	Description string `yaml:"description"`
+}
+
+// DefaultStateLabelColors lists the states that get labels, with their
+// default colours.
+var DefaultStateLabelColors = map[string]string{
+	"draft":                   "cfd3d7",
+	"paused":                  "cfd3d7",
+	"conflicting":             "b60205",
+	"checks-failing":          "d73a4a",
+	"checks-rerun":            "fef2c0",
+	"checks-pending":          "fef2c0",
+	"checks-blocked":          "e99695",
+	"optional-checks-failing": "ededed",
+	"bot-review-pending":      "c5def5",
+	"fixing-reviews":          "5319e7",
+	"awaiting-human":          "fbca04",
+	"reviews-pending":         "bfd4f2",
+	"ready":                   "0e8a16",
+	"merge-queued":            "c2e0c6",
+	"auto-merge-pending":      "c2e0c6",
+	"dequeued":                "e99695",
+	"gave-up":                 "000000",
+}
+
+var labelColorRe = regexp.MustCompile(`^[0-9a-fA-F]{6}$`)
+
+// IsStateLabel reports whether name is the label of a state, i.e. one
+// auto-claude adds and removes.
+func (c *StateLabelsConfig) IsStateLabel(name string) bool {
+	for _, label := range c.Labels {
+		if label.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// applyDefaults fills in the prefix and every state's label.
+func (c *StateLabelsConfig) applyDefaults() {
+	if c.Prefix == "" {
+		c.Prefix = "auto-claude:"
+	}
+	if c.Labels == nil {
+		c.Labels = make(map[string]StateLabel)
+	}
+	for state, color := range DefaultStateLabelColors {
+		label := c.Labels[state]
+		if label.Name == "" {
+			label.Name = c.Prefix + state
+		}
+		if label.Color == "" {
+			label.Color = color
+		}
+		label.Color = strings.TrimPrefix(label.Color, "#")
+		if label.Description == "" {
+			label.Description = "auto-claude state: " + strings.ReplaceAll(state, "-", " ")
+		}
+		c.Labels[state] = label
+	}
+}
+
+// IsStatusCheck reports whether a check is the one auto-claude publishes,
+// which never counts towards the PR's own state.
+func (r RepoConfig) IsStatusCheck(name string) bool {
+	return r.StatusCheck != nil && name == r.StatusCheck.Name
+}
+
+// FlakyConfig decides how often a failed Actions job is re-run before Claude
+// is asked to fix it. Counts are per head commit.
+type FlakyConfig struct {
+	MaxReruns        *int     `yaml:"max_reruns,omitempty"`       // for any failure
+	MaxFlakyReruns   *int     `yaml:"max_flaky_reruns,omitempty"` // for failures matching a known-flaky pattern
+	KnownFlakyChecks []string `yaml:"known_flaky_checks"`         // regexps matched against check names
+	KnownFlakyLogs   []string `yaml:"known_flaky_logs"`           // regexps matched against job logs
+
+	checkRes []*regexp.Regexp
+	logRes   []*regexp.Regexp
+}
+
+// IsFlakyCheck reports whether the check name matches a known-flaky pattern.
+func (f FlakyConfig) IsFlakyCheck(name string) bool {
+	return matchAny(f.checkRes, name)
+}
+
+// IsFlakyLog reports whether a job log matches a known-flaky pattern.
+func (f FlakyConfig) IsFlakyLog(log string) bool {
+	return matchAny(f.logRes, log)
+}
+
+func (f *FlakyConfig) compile() error {
+	var err error
+	if f.checkRes, err = compileAll(f.KnownFlakyChecks); err != nil {
+		return fmt.Errorf("known_flaky_checks: %w", err)
+	}
+	if f.logRes, err = compileAll(f.KnownFlakyLogs); err != nil {
+		return fmt.Errorf("known_flaky_logs: %w", err)
+	}
+	return nil
+}
+
+func compileAll(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Actions for completed checks that didn't pass.
+const (
+	CheckFailing = "failing" // have Claude fix the code
+	CheckRerun   = "rerun"   // re-run the check
+	CheckIgnore  = "ignore"  // treat as passed
+	CheckBlock   = "block"   // don't merge; wait for a human
+)
+
+// defaultCheckActions covers every conclusion other than success, neutral
+// and skipped, which always pass.
+var defaultCheckActions = map[string]string{
+	"failure":         CheckFailing,
+	"timed_out":       CheckFailing,
+	"startup_failure": CheckFailing,
+	"cancelled":       CheckRerun,
+	"stale":           CheckRerun,
+	"action_required": CheckBlock,
+}
+
+// CheckAction returns how to handle a completed check with the given
+// (lowercase) conclusion. Unknown conclusions block, so a new kind of
+// failure never lets a PR merge.
+func (r RepoConfig) CheckAction(conclusion string) string {
+	switch conclusion {
+	case "success", "neutral", "skipped":
+		return CheckIgnore
+	}
+	if action, ok := r.CheckConclusions[conclusion]; ok {
+		return action
+	}
+	if action, ok := defaultCheckActions[conclusion]; ok {
+		return action
+	}
+	return CheckBlock
+}
+
+// Actions for failing checks, by failure category
+const (
+	FailureFix    = "fix"    // have Claude fix the code
+	FailureRerun  = "rerun"  // re-run the job, up to flaky.max_flaky_reruns times
+	FailureNotify = "notify" // comment on the PR and wait for a human
+	FailureIgnore = "ignore" // treat as passed
+)
+
+// defaultFailureActions covers every failure category the CI classifier
+// knows. Infrastructure failures are re-run instead of fixed.
+var defaultFailureActions = map[string]string{
+	"compile_error":       FailureFix,
+	"test_failure":        FailureFix,
+	"lint":                FailureFix,
+	"timeout":             FailureRerun,
+	"dependency_download": FailureRerun,
+	"runner_lost":         FailureRerun,
+	"unknown":             FailureFix,
+}
+
+// FailureAction returns how to handle a failing check classified into
+// category.
+func (r RepoConfig) FailureAction(category string) string {
+	if action, ok := r.CIFailures[category]; ok {
+		return action
+	}
+	if action, ok := defaultFailureActions[category]; ok {
+		return action
+	}
+	return FailureFix
+}
+
+// CommitMessageConfig holds text/template templates for squash commit
+// messages. Empty templates keep GitHub's default.
+type CommitMessageConfig struct {
+	Title     string `yaml:"title"`
+	Body      string `yaml:"body"`
+	Summarize bool   `yaml:"summarize"` // have Claude summarize the squashed commits as {{.Summary}}
+}
+
+// CopilotBot is the name of the built-in review bot for GitHub Copilot.
+const CopilotBot = "copilot"
+
+// ReviewBot is an AI reviewer such as Copilot, CodeRabbit or Gemini. In YAML
+// it is either a bare login or a mapping.
+type ReviewBot struct {
+	Name     string   `yaml:"name"`     // shown in states, defaults to the first login
+	Logins   []string `yaml:"logins"`   // logins its reviews and comments appear under; "[bot]" suffixes match too
+	Request  string   `yaml:"request"`  // login to request reviews of each head commit from; empty if it reviews on its own
+	Required bool     `yaml:"required"` // wait for its review of the head commit before merging
+	AutoFix  bool     `yaml:"auto_fix"` // have Claude fix its unresolved threads
+}
+
+func (b *ReviewBot) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var login string
+		if err := node.Decode(&login); err != nil {
+			return err
+		}
+		*b = ReviewBot{Name: login, Logins: []string{login}, Request: login}
+		return nil
+	}
+	type plain ReviewBot
+	return node.Decode((*plain)(b))
+}
+
+// IsLogin reports whether login belongs to the bot.
+func (b ReviewBot) IsLogin(login string) bool {
+	login = strings.TrimSuffix(login, "[bot]")
+	for _, l := range b.Logins {
+		if strings.TrimSuffix(l, "[bot]") == login {
+			return true
+		}
+	}
+	return false
+}
+
+// renovateLogins are the logins Renovate opens PRs under.
+var renovateLogins = []string{"renovate", "renovate-bot", "app/renovate"}
+
+// IsRenovateLogin reports whether login is Renovate's. Like IsLogin, a "[bot]"
+// suffix matches too.
+func IsRenovateLogin(login string) bool {
+	return slices.Contains(renovateLogins, strings.TrimSuffix(login, "[bot]"))
+}
+
+func copilotBot(required bool) ReviewBot {
+	return ReviewBot{
+		Name:     CopilotBot,
+		Logins:   []string{"copilot-pull-request-reviewer", "Copilot", "copilot", "github-copilot"},
+		Request:  "copilot-pull-request-reviewer",
+		Required: required,
+		AutoFix:  true,
+	}
+}
+
+// withCopilotRequired returns a copy of bots with Copilot's review required
+// or not.
+func withCopilotRequired(bots []ReviewBot, required bool) []ReviewBot {
+	bots = slices.Clone(bots)
+	for i := range bots {
+		if bots[i].Name == CopilotBot {
+			bots[i].Required = required
+		}
+	}
+	return bots
+}
+
+// BaseBranch selects PRs by base branch name or path.Match glob, optionally
+// overriding repo settings for them. In YAML it is either a bare name or a
+// mapping with overrides.
+type BaseBranch struct {
+	Name                 string `yaml:"name"`
+	MergeMethod          string `yaml:"merge_method"`
+	MergeStrategy        string `yaml:"merge_strategy"`
+	RequireCopilotReview *bool  `yaml:"require_copilot_review,omitempty"`
+}
+
+func (b *BaseBranch) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&b.Name)
+	}
+	type plain BaseBranch
+	return node.Decode((*plain)(b))
+}
+
+// ForBase returns the repo config in effect for PRs targeting base, with
+// BaseBranch set to base and the first matching entry's overrides applied.
+// ok is false when base matches no configured base branch.
+func (r RepoConfig) ForBase(base string) (RepoConfig, bool) {
+	for _, b := range r.BaseBranches {
+		if matched, _ := path.Match(b.Name, base); !matched {
+			continue
+		}
+		eff := r
+		eff.BaseBranch = base
+		if b.MergeMethod != "" {
+			eff.MergeMethod = b.MergeMethod
+		}
+		if b.MergeStrategy != "" {
+			eff.MergeStrategy = b.MergeStrategy
+		}
+		if b.RequireCopilotReview != nil {
+			eff.RequireCopilotReview = b.RequireCopilotReview
+			eff.ReviewBots = withCopilotRequired(r.ReviewBots, *b.RequireCopilotReview)
+		}
+		return eff, true
+	}
+	return r, false
 }
 
 type ReviewRequestComment struct {
@@ -39,6 +458,24 @@ type ReviewRequestComment struct {
 	Message string `yaml:"message"`
 }
 
+// HumanReviewsConfig opts in to Claude addressing review threads and review
+// bodies of the listed reviewers. Teams are "org/slug", or a bare slug of a
+// team in the repo owner's org.
+type HumanReviewsConfig struct {
+	Reviewers      []string `yaml:"reviewers"`
+	Teams          []string `yaml:"teams"`
+	ResolveThreads bool     `yaml:"resolve_threads"` // resolve threads after replying; by default the reviewer does
+}
+
+type WebhookConfig struct {
+	Enabled         bool          `yaml:"enabled"`
+	Listen          string        `yaml:"listen"`
+	Path            string        `yaml:"path"`
+	SecretEnv       string        `yaml:"secret_env"`
+	PollInterval    time.Duration `yaml:"-"`
+	RawPollInterval string        `yaml:"poll_interval"` // safety-net polling while webhooks are enabled
+}
+
 type LogConfig struct {
 	Level string `yaml:"level"`
 }
@@ -86,9 +523,24 @@ func (c *Config) setDefaults() error {
 	if c.LogFile == "" {
 		c.LogFile = c.Workdir + "/logs/auto-claude.log"
 	}
+	if c.StateFile == "" {
+		c.StateFile = c.Workdir + "/state.json"
+	}
 	if c.Claude.Model == "" {
 		c.Claude.Model = "opus"
 	}
+	if c.GitHub.Backend == "" {
+		c.GitHub.Backend = "gh"
+	}
+	if c.GitHub.Host == "" {
+		c.GitHub.Host = "github.com"
+	}
+	if c.GitHub.APIURL == "" {
+		c.GitHub.APIURL = apiURL(c.GitHub.Host)
+	}
+	if c.GitHub.TokenEnv == "" {
+		c.GitHub.TokenEnv = "GITHUB_TOKEN"
+	}
 	if c.Log.Level == "" {
 		c.Log.Level = "info"
 	}
@@ -105,20 +557,101 @@ func (c *Config) setDefaults() error {
 	}
 	c.TUI.RefreshInterval = tuiInterval
 
+	if c.Webhook.Listen == "" {
+		c.Webhook.Listen = ":8080"
+	}
+	if c.Webhook.Path == "" {
+		c.Webhook.Path = "/webhook"
+	}
+	if c.Webhook.SecretEnv == "" {
+		c.Webhook.SecretEnv = "AUTO_CLAUDE_WEBHOOK_SECRET"
+	}
+	if c.Webhook.RawPollInterval == "" {
+		c.Webhook.RawPollInterval = "10m"
+	}
+	webhookInterval, err := time.ParseDuration(c.Webhook.RawPollInterval)
+	if err != nil {
+		return fmt.Errorf("parse webhook.poll_interval %q: %w", c.Webhook.RawPollInterval, err)
+	}
+	if webhookInterval <= 0 {
+		return fmt.Errorf("webhook.poll_interval must be positive, got %s", c.Webhook.RawPollInterval)
+	}
+	c.Webhook.PollInterval = webhookInterval
+
 	for i := range c.Repos {
+		if len(c.Repos[i].BaseBranches) > 0 && c.Repos[i].BaseBranch != "" {
+			return fmt.Errorf("repos[%d]: set base_branch or base_branches, not both", i)
+		}
+		if c.Repos[i].Host == "" {
+			c.Repos[i].Host = c.GitHub.Host
+		}
 		if c.Repos[i].BaseBranch == "" {
 			c.Repos[i].BaseBranch = "main"
 		}
+		if len(c.Repos[i].BaseBranches) == 0 {
+			c.Repos[i].BaseBranches = []BaseBranch{{Name: c.Repos[i].BaseBranch}}
+		}
 		if c.Repos[i].MergeMethod == "" {
 			c.Repos[i].MergeMethod = "squash"
 		}
+		if c.Repos[i].MergeStrategy == "" {
+			c.Repos[i].MergeStrategy = "direct"
+		}
 		if c.Repos[i].MaxConcurrentPRs == 0 {
 			c.Repos[i].MaxConcurrentPRs = 3
 		}
+		if c.Repos[i].Flaky.MaxReruns == nil {
+			one := 1
+			c.Repos[i].Flaky.MaxReruns = &one
+		}
+		if c.Repos[i].Flaky.MaxFlakyReruns == nil {
+			three := 3
+			c.Repos[i].Flaky.MaxFlakyReruns = &three
+		}
+		if c.Repos[i].MaxFixAttempts == nil {
+			three := 3
+			c.Repos[i].MaxFixAttempts = &three
+		}
+		if sc := c.Repos[i].StatusCheck; sc != nil {
+			if sc.Name == "" {
+				sc.Name = "auto-claude"
+			}
+			if sc.Kind == "" {
+				// Only apps can create check runs
+				sc.Kind = StatusCheckStatus
+				if c.GitHub.App != nil {
+					sc.Kind = StatusCheckRun
+				}
+			}
+			if sc.GiveUpConclusion == "" {
+				sc.GiveUpConclusion = "neutral"
+			}
+		}
+		if sc := c.Repos[i].StatusComment; sc != nil && sc.MaxEntries == 0 {
+			sc.MaxEntries = 50
+		}
+		if sl := c.Repos[i].StateLabels; sl != nil {
+			sl.applyDefaults()
+		}
+		if err := c.Repos[i].Flaky.compile(); err != nil {
+			return fmt.Errorf("repos[%d].flaky: %w", i, err)
+		}
 		if c.Repos[i].RequireCopilotReview == nil {
 			defaultTrue := true
 			c.Repos[i].RequireCopilotReview = &defaultTrue
 		}
+		hasCopilot := false
+		for j := range c.Repos[i].ReviewBots {
+			bot := &c.Repos[i].ReviewBots[j]
+			if bot.Name == "" && len(bot.Logins) > 0 {
+				bot.Name = bot.Logins[0]
+			}
+			hasCopilot = hasCopilot || bot.Name == CopilotBot
+		}
+		if !hasCopilot {
+			copilot := copilotBot(*c.Repos[i].RequireCopilotReview)
+			c.Repos[i].ReviewBots = append([]ReviewBot{copilot}, c.Repos[i].ReviewBots...)
+		}
 	}
 
 	return nil
@@ -128,6 +661,19 @@ func (c *Config) validate() error {
 	if len(c.Repos) == 0 {
 		return fmt.Errorf("no repos configured")
 	}
+	switch c.GitHub.Backend {
+	case "gh", "api":
+	default:
+		return fmt.Errorf("invalid github.backend %q (gh|api)", c.GitHub.Backend)
+	}
+	if app := c.GitHub.App; app != nil {
+		if app.ID <= 0 {
+			return fmt.Errorf("github.app.id required")
+		}
+		if app.PrivateKeyPath == "" {
+			return fmt.Errorf("github.app.private_key_path required")
+		}
+	}
 	for i, r := range c.Repos {
 		if r.Owner == "" {
 			return fmt.Errorf("repos[%d]: owner required", i)
@@ -135,10 +681,107 @@ func (c *Config) validate() error {
 		if r.Name == "" {
 			return fmt.Errorf("repos[%d]: name required", i)
 		}
+		if strings.Contains(r.Host, "/") {
+			return fmt.Errorf("repos[%d]: host %q must be a bare hostname", i, r.Host)
+		}
 		switch r.MergeMethod {
-		case "squash", "merge":
+		case "squash", "merge", "rebase":
 		default:
-			return fmt.Errorf("repos[%d]: invalid merge_method %q (squash|merge)", i, r.MergeMethod)
+			return fmt.Errorf("repos[%d]: invalid merge_method %q (squash|merge|rebase)", i, r.MergeMethod)
+		}
+		if !validMergeStrategy(r.MergeStrategy) {
+			return fmt.Errorf("repos[%d]: invalid merge_strategy %q (direct|auto_merge|merge_queue)", i, r.MergeStrategy)
+		}
+		for j, b := range r.BaseBranches {
+			if b.Name == "" {
+				return fmt.Errorf("repos[%d].base_branches[%d]: name required", i, j)
+			}
+			if _, err := path.Match(b.Name, ""); err != nil {
+				return fmt.Errorf("repos[%d].base_branches[%d]: invalid pattern %q: %w", i, j, b.Name, err)
+			}
+			switch b.MergeMethod {
+			case "", "squash", "merge", "rebase":
+			default:
+				return fmt.Errorf("repos[%d].base_branches[%d]: invalid merge_method %q (squash|merge|rebase)", i, j, b.MergeMethod)
+			}
+			if b.MergeStrategy != "" && !validMergeStrategy(b.MergeStrategy) {
+				return fmt.Errorf("repos[%d].base_branches[%d]: invalid merge_strategy %q (direct|auto_merge|merge_queue)", i, j, b.MergeStrategy)
+			}
+		}
+		if cm := r.CommitMessage; cm != nil {
+			if _, err := template.New("title").Parse(cm.Title); err != nil {
+				return fmt.Errorf("repos[%d]: commit_message.title: %w", i, err)
+			}
+			if _, err := template.New("body").Parse(cm.Body); err != nil {
+				return fmt.Errorf("repos[%d]: commit_message.body: %w", i, err)
+			}
+		}
+		for conclusion, action := range r.CheckConclusions {
+			if _, ok := defaultCheckActions[conclusion]; !ok {
+				return fmt.Errorf("repos[%d]: check_conclusions: unknown conclusion %q", i, conclusion)
+			}
+			switch action {
+			case CheckFailing, CheckRerun, CheckIgnore, CheckBlock:
+			default:
+				return fmt.Errorf("repos[%d]: check_conclusions.%s: invalid action %q (failing|rerun|ignore|block)", i, conclusion, action)
+			}
+		}
+		if h := r.HumanReviews; h != nil && len(h.Reviewers) == 0 && len(h.Teams) == 0 {
+			return fmt.Errorf("repos[%d]: human_reviews needs reviewers or teams", i)
+		}
+		botNames := make(map[string]bool)
+		for j, bot := range r.ReviewBots {
+			if len(bot.Logins) == 0 {
+				return fmt.Errorf("repos[%d].review_bots[%d]: logins required", i, j)
+			}
+			if botNames[bot.Name] {
+				return fmt.Errorf("repos[%d].review_bots[%d]: duplicate name %q", i, j, bot.Name)
+			}
+			botNames[bot.Name] = true
+		}
+		for category, action := range r.CIFailures {
+			if _, ok := defaultFailureActions[category]; !ok {
+				return fmt.Errorf("repos[%d]: ci_failures: unknown category %q", i, category)
+			}
+			switch action {
+			case FailureFix, FailureRerun, FailureNotify, FailureIgnore:
+			default:
+				return fmt.Errorf("repos[%d]: ci_failures.%s: invalid action %q (fix|rerun|notify|ignore)", i, category, action)
+			}
+		}
+		if *r.Flaky.MaxReruns < 0 || *r.Flaky.MaxFlakyReruns < 0 {
+			return fmt.Errorf("repos[%d]: flaky rerun limits must not be negative", i)
+		}
+		if *r.MaxFixAttempts < 0 {
+			return fmt.Errorf("repos[%d]: max_fix_attempts must not be negative", i)
+		}
+		if sc := r.StatusCheck; sc != nil {
+			switch sc.Kind {
+			case StatusCheckRun, StatusCheckStatus:
+			default:
+				return fmt.Errorf("repos[%d]: invalid status_check.kind %q (check_run|status)", i, sc.Kind)
+			}
+			switch sc.GiveUpConclusion {
+			case "neutral", "failure":
+			default:
+				return fmt.Errorf("repos[%d]: invalid status_check.give_up_conclusion %q (neutral|failure)", i, sc.GiveUpConclusion)
+			}
+		}
+		if sc := r.StatusComment; sc != nil && (sc.MaxEntries < 0 || sc.MaxEntries > MaxStatusCommentEntries) {
+			return fmt.Errorf("repos[%d]: status_comment.max_entries must be between 0 and %d", i, MaxStatusCommentEntries)
+		}
+		if sl := r.StateLabels; sl != nil {
+			for state, label := range sl.Labels {
+				if _, ok := DefaultStateLabelColors[state]; !ok {
+					return fmt.Errorf("repos[%d]: state_labels.labels: unknown state %q", i, state)
+				}
+				if !labelColorRe.MatchString(label.Color) {
+					return fmt.Errorf("repos[%d]: state_labels.labels.%s: invalid color %q (6 hex digits)", i, state, label.Color)
+				}
+			}
+		}
+		if r.MaxPRs < 0 {
+			return fmt.Errorf("repos[%d]: max_prs must not be negative", i)
 		}
 		if r.ReviewRequestComment != nil && r.ReviewRequestComment.Enabled && r.ReviewRequestComment.Message == "" {
 			return fmt.Errorf("repos[%d]: review_request_comment.message required when enabled", i)
@@ -146,3 +789,12 @@ func (c *Config) validate() error {
 	}
 	return nil
 }
+
+func validMergeStrategy(s string) bool {
+	switch s {
+	case "direct", "auto_merge", "merge_queue":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,48 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+)
+
+// ttlCache holds values fetched from GitHub for a while, to bound the API
+// calls per poll. A failed fetch keeps the previous value, stale or zero,
+// since a stale answer beats none; it's retried after the TTL either way.
+// All methods are safe for concurrent use.
+type ttlCache[V any] struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]ttlEntry[V]
+}
+
+type ttlEntry[V any] struct {
+	value   V
+	fetched time.Time
+}
+
+func newTTLCache[V any](ttl time.Duration) *ttlCache[V] {
+	return &ttlCache[V]{ttl: ttl, entries: make(map[string]ttlEntry[V])}
+}
+
+// get returns the value cached for key, fetching it if it's missing or
+// older than the TTL. With a fetch error it returns the previous value
+// along with the error.
+func (c *ttlCache[V]) get(key string, fetch func() (V, error)) (V, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Since(entry.fetched) < c.ttl {
+		return entry.value, nil
+	}
+
+	value, err := fetch()
+	if err != nil {
+		value = entry.value
+	}
+
+	c.mu.Lock()
+	c.entries[key] = ttlEntry[V]{value: value, fetched: time.Now()}
+	c.mu.Unlock()
+	return value, err
+}
@@ -0,0 +1,34 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTTLCacheKeepsStaleValueOnError(t *testing.T) {
+	c := newTTLCache[string](time.Hour)
+	fetches := 0
+	fetch := func(value string, err error) func() (string, error) {
+		return func() (string, error) {
+			fetches++
+			return value, err
+		}
+	}
+
+	if got, err := c.get("k", fetch("one", nil)); got != "one" || err != nil {
+		t.Fatalf("get = %q, %v, want one", got, err)
+	}
+	if got, _ := c.get("k", fetch("two", nil)); got != "one" || fetches != 1 {
+		t.Errorf("get = %q after %d fetches, want the cached one without fetching", got, fetches)
+	}
+
+	// Expire the entry; a failed refresh keeps the stale value
+	c.ttl = 0
+	if got, err := c.get("k", fetch("", errors.New("boom"))); got != "one" || err == nil {
+		t.Errorf("get = %q, %v, want one and the error", got, err)
+	}
+	if got, _ := c.get("k", fetch("three", nil)); got != "three" {
+		t.Errorf("get = %q, want three once fetching works again", got)
+	}
+}
@@ -0,0 +1,237 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/marcin-skalski/auto-claude/internal/config"
+	"github.com/marcin-skalski/auto-claude/internal/github"
+	"github.com/marcin-skalski/auto-claude/internal/state"
+	"github.com/marcin-skalski/auto-claude/internal/worker"
+)
+
+// commandPrefix starts a command line in a PR comment, e.g.
+// "/auto-claude pause".
+const commandPrefix = "/auto-claude"
+
+const commandUsage = "Commands: `pause`, `resume`, `stop`, `retry`, `fix-checks`, `fix-reviews`, `rebase`, `merge-method squash|merge|rebase`."
+
+// parseCommand returns the command on the first line of body that starts
+// with commandPrefix.
+func parseCommand(body string) (name string, args []string, ok bool) {
+	for _, line := range strings.Split(body, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != commandPrefix {
+			continue
+		}
+		if len(fields) == 1 {
+			return "", nil, true
+		}
+		return strings.ToLower(fields[1]), fields[2:], true
+	}
+	return "", nil, false
+}
+
+// handleCommands runs the commands in the PR comments posted since the last
+// one checked, oldest first. The last checked comment is kept in the state
+// store; a command is recorded as handled once it ran, so a failed reaction
+// or reply doesn't run it again.
+func (d *Daemon) handleCommands(ctx context.Context, repo config.RepoConfig, pr github.PRInfo) {
+	key := workerKey(repo, pr.Number)
+	seen := d.state.Get(key)
+	comments := pr.Comments
+	if len(comments) == 0 || comments[len(comments)-1].ID == seen.LastCommentID {
+		return
+	}
+
+	// The PR only comes with its latest comments; fetch the rest of those
+	// since the last checked one if it's older
+	if pr.OlderComments && comments[0].ID != seen.LastCommentID && !comments[0].CreatedAt.Before(seen.LastCommentAt) {
+		var err error
+		comments, err = d.gh(repo).GetCommentsSince(ctx, repo.Owner, repo.Name, pr.Number, seen.LastCommentAt)
+		if err != nil {
+			d.logger.Error("failed to get comments", "key", key, "err", err)
+			return
+		}
+	}
+
+	fresh := newComments(comments, seen)
+	for _, c := range fresh {
+		name, args, ok := parseCommand(c.Body)
+		// Without a last checked comment, e.g. one recorded before
+		// upgrading, acknowledged commands count as handled
+		if !ok || seen.LastCommentID == "" && c.Reacted {
+			continue
+		}
+		d.handleCommand(ctx, repo, pr, c, name, args)
+		if !d.checkedComment(key, c) {
+			return
+		}
+	}
+	if len(fresh) > 0 {
+		d.checkedComment(key, fresh[len(fresh)-1])
+	}
+}
+
+// checkedComment records c as the last comment of a PR checked for commands.
+func (d *Daemon) checkedComment(key string, c github.Comment) bool {
+	if err := d.state.Update(key, func(s *state.PR) { s.LastCommentID, s.LastCommentAt = c.ID, c.CreatedAt }); err != nil {
+		d.logger.Error("failed to save state", "key", key, "err", err)
+		return false
+	}
+	return true
+}
+
+// newComments returns the comments after the last checked one in seen.
+func newComments(comments []github.Comment, seen state.PR) []github.Comment {
+	if seen.LastCommentID == "" {
+		return comments
+	}
+	for i, c := range slices.Backward(comments) {
+		if c.ID == seen.LastCommentID {
+			return comments[i+1:]
+		}
+	}
+	// The last checked comment was deleted
+	for i, c := range comments {
+		if c.CreatedAt.After(seen.LastCommentAt) {
+			return comments[i:]
+		}
+	}
+	return nil
+}
+
+func (d *Daemon) handleCommand(ctx context.Context, repo config.RepoConfig, pr github.PRInfo, c github.Comment, name string, args []string) {
+	key := workerKey(repo, pr.Number)
+	logger := d.logger.With("key", key, "command", name, "author", c.Author)
+	gh := d.gh(repo)
+
+	var reply string
+	err := d.authorizeCommand(ctx, repo, c.Author)
+	if err == nil {
+		reply, err = d.runCommand(ctx, repo, pr, name, args)
+	}
+	reaction := github.ReactionThumbsUp
+	if err != nil {
+		logger.Warn("command rejected", "err", err)
+		reaction = github.ReactionConfused
+		reply = err.Error()
+	} else {
+		logger.Info("command run")
+	}
+
+	if err := gh.AddReaction(ctx, c.ID, reaction); err != nil {
+		logger.Error("failed to acknowledge command", "err", err)
+	}
+	body := fmt.Sprintf("@%s %s", c.Author, reply)
+	if err := gh.PostComment(ctx, repo.Owner, repo.Name, pr.Number, body); err != nil {
+		logger.Error("failed to reply to command", "err", err)
+	}
+}
+
+// authorizeCommand accepts commands from users with write access.
+func (d *Daemon) authorizeCommand(ctx context.Context, repo config.RepoConfig, login string) error {
+	permission, err := d.gh(repo).GetPermission(ctx, repo.Owner, repo.Name, login)
+	if err != nil {
+		d.logger.Error("failed to check command permission", "repo", repo.Owner+"/"+repo.Name, "author", login, "err", err)
+		return errors.New("couldn't check your permission on this repo, please try again")
+	}
+	if permission != "admin" && permission != "write" {
+		return errors.New("only users with write access can run auto-claude commands")
+	}
+	return nil
+}
+
+// runCommand runs a command and returns the reply to its author. The error
+// is the reply for commands that can't be run.
+func (d *Daemon) runCommand(ctx context.Context, repo config.RepoConfig, pr github.PRInfo, name string, args []string) (string, error) {
+	key := workerKey(repo, pr.Number)
+	switch name {
+	case "pause":
+		if err := d.updateState(key, func(s *state.PR) { s.Paused = true }); err != nil {
+			return "", err
+		}
+		return "paused. A running action finishes, then nothing happens on this PR until `/auto-claude resume`.", nil
+
+	case "stop":
+		if err := d.updateState(key, func(s *state.PR) { s.Paused = true }); err != nil {
+			return "", err
+		}
+		d.mu.Lock()
+		cancel, running := d.workers[key]
+		d.mu.Unlock()
+		if running {
+			cancel()
+			return "stopped the running action and paused until `/auto-claude resume`.", nil
+		}
+		return "nothing was running; paused until `/auto-claude resume`.", nil
+
+	case "resume":
+		if err := d.updateState(key, func(s *state.PR) { s.Paused = false }); err != nil {
+			return "", err
+		}
+		return "resumed.", nil
+
+	case "retry":
+		d.memoryFor(key).Reset()
+		return "forgot earlier re-runs, failure categories and fix attempts; failing checks get re-run and classified again.", nil
+
+	case worker.RequestFixChecks:
+		d.memoryFor(key).Request(worker.RequestFixChecks)
+		return "Claude fixes the failing checks on the next run, whatever their failure category.", nil
+
+	case worker.RequestFixReviews:
+		d.memoryFor(key).Request(worker.RequestFixReviews)
+		return "Claude addresses all unresolved review bot threads on the next run.", nil
+
+	case "rebase":
+		if err := d.gh(repo).RebaseBranch(ctx, repo.Owner, repo.Name, pr.Number); err != nil {
+			return "", fmt.Errorf("rebase failed: %v", err)
+		}
+		return fmt.Sprintf("rebased onto `%s`.", pr.BaseRef), nil
+
+	case "merge-method":
+		if len(args) != 1 {
+			return "", errors.New("usage: `/auto-claude merge-method squash|merge|rebase`")
+		}
+		method := strings.ToLower(args[0])
+		switch method {
+		case "squash", "merge", "rebase":
+		default:
+			return "", fmt.Errorf("unknown merge method %q, use squash, merge or rebase", args[0])
+		}
+		if err := d.updateState(key, func(s *state.PR) { s.MergeMethod = method }); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("this PR will be merged with `%s`.", method), nil
+
+	case "":
+		return "", errors.New(commandUsage)
+
+	default:
+		return "", fmt.Errorf("unknown command %q. %s", name, commandUsage)
+	}
+}
+
+func (d *Daemon) updateState(key string, update func(*state.PR)) error {
+	if err := d.state.Update(key, update); err != nil {
+		d.logger.Error("failed to save state", "key", key, "err", err)
+		return errors.New("couldn't save the change, please try again")
+	}
+	return nil
+}
+
+// memoryFor returns the memory of a PR, creating it if needed.
+func (d *Daemon) memoryFor(key string) *worker.Memory {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	memory, ok := d.memory[key]
+	if !ok {
+		memory = worker.NewMemory()
+		d.memory[key] = memory
+	}
+	return memory
+}
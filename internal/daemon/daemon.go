@@ -2,15 +2,17 @@ package daemon
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/marcin-skalski/auto-claude/internal/claude"
 	"github.com/marcin-skalski/auto-claude/internal/config"
-	"github.com/marcin-skalski/auto-claude/internal/git"
 	"github.com/marcin-skalski/auto-claude/internal/github"
+	"github.com/marcin-skalski/auto-claude/internal/state"
 	"github.com/marcin-skalski/auto-claude/internal/tui"
 	"github.com/marcin-skalski/auto-claude/internal/worker"
 )
@@ -26,46 +28,94 @@ type claudeSession struct {
 
 type Daemon struct {
 	cfg    *config.Config
-	gh     *github.Client
-	claude *claude.Client
-	git    *git.Client
+	gh     func(config.RepoConfig) worker.GitHub
+	claude worker.Agent
+	git    worker.Git
+	state  *state.Store // per-PR settings made with comment commands
 	logger *slog.Logger
 
 	mu      sync.Mutex
 	workers map[string]context.CancelFunc
+	memory  map[string]*worker.Memory // key: owner/repo#number, dropped when the PR closes
 	wg      sync.WaitGroup
 
 	sessionsMu     sync.Mutex
 	claudeSessions map[string]*claudeSession
 
-	prCacheMu              sync.Mutex
-	prCache                map[string][]github.PRInfo // key: owner/repo
-	copilotReviewCache     map[string]bool            // key: owner/repo#number, value: has Copilot reviewed
-	copilotUnresolvedCache map[string]bool            // key: owner/repo#number, value: has unresolved Copilot threads
+	prCacheMu sync.Mutex
+	prCache   map[string][]github.PRInfo // key: owner/repo, with reviews and threads
+
+	required *ttlCache[github.RequiredChecks] // key: host/owner/repo@base
+
+	teams *ttlCache[[]string] // key: host/org/team
+
+	labelsMu sync.Mutex
+	labels   map[string]bool // key: owner/repo@name, state labels known to exist as configured
+
+	triggerMu sync.Mutex
+	triggered []string      // keys of repos to poll now (webhook deliveries), each once
+	triggers  chan struct{} // signals triggered isn't empty
+
+	lastPoll time.Time
 }
 
-func New(cfg *config.Config, gh *github.Client, cl *claude.Client, g *git.Client, logger *slog.Logger) *Daemon {
-	return &Daemon{
-		cfg:                    cfg,
-		gh:                     gh,
-		claude:                 cl,
-		git:                    g,
-		logger:                 logger,
-		workers:                make(map[string]context.CancelFunc),
-		claudeSessions:         make(map[string]*claudeSession),
-		prCache:                make(map[string][]github.PRInfo),
-		copilotReviewCache:     make(map[string]bool),
-		copilotUnresolvedCache: make(map[string]bool),
-	}
+// New creates a daemon. gh returns the GitHub client for a repo's host.
+func New(cfg *config.Config, gh func(config.RepoConfig) worker.GitHub, cl worker.Agent, g worker.Git, st *state.Store, logger *slog.Logger) *Daemon {
+	d := &Daemon{
+		cfg:            cfg,
+		gh:             gh,
+		claude:         cl,
+		git:            g,
+		state:          st,
+		logger:         logger,
+		workers:        make(map[string]context.CancelFunc),
+		memory:         make(map[string]*worker.Memory),
+		claudeSessions: make(map[string]*claudeSession),
+		prCache:        make(map[string][]github.PRInfo),
+		required:       newTTLCache[github.RequiredChecks](requiredChecksTTL),
+		teams:          newTTLCache[[]string](teamMembersTTL),
+		labels:         make(map[string]bool),
+		triggers:       make(chan struct{}, 1),
+	}
+	if err := d.migrateStateKeys(); err != nil {
+		logger.Error("failed to migrate state", "err", err)
+	}
+	return d
+}
+
+// migrateStateKeys adds the host to state keys saved before they had one.
+func (d *Daemon) migrateStateKeys() error {
+	return d.state.Rename(func(key string) string {
+		repoPart, number, ok := strings.Cut(key, "#")
+		owner, name, ok2 := strings.Cut(repoPart, "/")
+		if !ok || !ok2 || strings.Contains(name, "/") {
+			return key
+		}
+		for _, repo := range d.cfg.Repos {
+			if repo.Owner == owner && repo.Name == name {
+				return repoKey(repo) + "#" + number
+			}
+		}
+		return key
+	})
 }
 
 func (d *Daemon) Run(ctx context.Context) error {
-	d.logger.Info("daemon started", "poll_interval", d.cfg.PollInterval, "repos", len(d.cfg.Repos))
+	pollInterval := d.cfg.PollInterval
+	if d.cfg.Webhook.Enabled {
+		if err := d.startWebhookServer(ctx); err != nil {
+			return err
+		}
+		// Webhooks drive most polls; the ticker only catches missed deliveries
+		pollInterval = d.cfg.Webhook.PollInterval
+	}
+
+	d.logger.Info("daemon started", "poll_interval", pollInterval, "webhook", d.cfg.Webhook.Enabled, "repos", len(d.cfg.Repos))
 
 	// Initial poll
 	d.poll(ctx)
 
-	ticker := time.NewTicker(d.cfg.PollInterval)
+	ticker := time.NewTicker(pollInterval)
 	defer ticker.Stop()
 
 	statusTicker := time.NewTicker(5 * time.Second)
@@ -80,7 +130,18 @@ func (d *Daemon) Run(ctx context.Context) error {
 			d.logger.Info("all workers stopped")
 			return nil
 		case <-ticker.C:
+			if d.deferPoll(pollInterval) {
+				continue
+			}
 			d.poll(ctx)
+		case <-d.triggers:
+			for _, key := range d.takeTriggered() {
+				if d.rateLimitFor(key).Low() {
+					// The next (stretched) scheduled poll picks this up
+					continue
+				}
+				d.pollTriggered(ctx, key)
+			}
 		case <-statusTicker.C:
 			d.logClaudeStatus()
 		}
@@ -88,104 +149,86 @@ func (d *Daemon) Run(ctx context.Context) error {
 }
 
 func (d *Daemon) poll(ctx context.Context) {
+	d.lastPoll = time.Now()
 	for _, repo := range d.cfg.Repos {
+		// Quotas are per host; an exhausted one only holds back its own repos
+		if limit := d.gh(repo).RateLimit(); limit.Exhausted() {
+			d.logger.Warn("API quota exhausted, skipping repo", "repo", repo.Owner+"/"+repo.Name, "host", repo.Host, "reset", limit.Reset)
+			continue
+		}
 		if err := d.pollRepo(ctx, repo); err != nil {
+			var rateLimited *github.RateLimitError
+			if errors.As(err, &rateLimited) {
+				d.logger.Warn("rate limited, skipping repo", "repo", repo.Owner+"/"+repo.Name, "host", repo.Host, "reset", rateLimited.Reset)
+				continue
+			}
 			d.logger.Error("poll repo failed", "repo", repo.Owner+"/"+repo.Name, "err", err)
 		}
 	}
 }
 
-func (d *Daemon) pollRepo(ctx context.Context, repo config.RepoConfig) error {
-	prs, err := d.gh.ListOpenPRs(ctx, repo.Owner, repo.Name)
-	if err != nil {
-		return fmt.Errorf("list PRs: %w", err)
+// deferPoll stretches polling as the API quota runs low, so the remaining
+// requests last until the quota resets.
+func (d *Daemon) deferPoll(interval time.Duration) bool {
+	limit := d.rateLimit()
+	factor := limit.PollFactor()
+	if factor == 1 {
+		return false
+	}
+	// Half an interval of slack absorbs ticker jitter
+	if time.Since(d.lastPoll) < time.Duration(factor)*interval-interval/2 {
+		d.logger.Info("API quota low, stretching poll interval",
+			"resource", limit.Resource,
+			"remaining", limit.Remaining,
+			"limit", limit.Limit,
+			"factor", factor)
+		return true
 	}
+	return false
+}
 
-	// Cache PR data for TUI snapshot
-	repoKey := repo.Owner + "/" + repo.Name
-
-	// Fetch Copilot review status outside lock to avoid blocking cache reads during network calls
-	type copilotStatus struct {
-		prKey                string
-		hasCopilotReview     bool
-		hasUnresolvedCopilot bool
+// rateLimit returns the most constrained quota across the hosts of all
+// configured repos.
+func (d *Daemon) rateLimit() github.RateLimit {
+	limits := make([]github.RateLimit, 0, len(d.cfg.Repos))
+	for _, repo := range d.cfg.Repos {
+		limits = append(limits, d.gh(repo).RateLimit())
 	}
-	var copilotStatuses []copilotStatus
-
-	if *repo.RequireCopilotReview {
-		for _, pr := range prs {
-			prKey := workerKey(repo.Owner, repo.Name, pr.Number)
-
-			// Skip fetching review threads for Renovate PRs (mirrors worker behavior)
-			if isRenovateAuthor(pr.Author.Login) {
-				copilotStatuses = append(copilotStatuses, copilotStatus{
-					prKey:                prKey,
-					hasCopilotReview:     false,
-					hasUnresolvedCopilot: false,
-				})
-				continue
-			}
-
-			// Fetch reviews to check Copilot status
-			hasCopilotReview := false
-			hasUnresolvedCopilot := false
-
-			// Check top-level reviews
-			reviews, err := d.gh.GetReviews(ctx, repo.Owner, repo.Name, pr.Number)
-			if err != nil {
-				d.logger.Warn("failed to get reviews for Copilot status", "pr", pr.Number, "err", err)
-				// Skip cache update for this PR on error - preserve previous value
-				continue
-			}
-
-			for _, r := range reviews {
-				if isCopilotAuthor(r.Author) && r.State != "PENDING" && r.State != "DISMISSED" {
-					hasCopilotReview = true
-					break
-				}
-			}
+	return github.LowestRateLimit(limits...)
+}
 
-			// Only fetch review threads if Copilot review exists
-			if hasCopilotReview {
-				threads, err := d.gh.GetReviewThreads(ctx, repo.Owner, repo.Name, pr.Number)
-				if err != nil {
-					d.logger.Warn("failed to get review threads for Copilot status", "pr", pr.Number, "err", err)
-					// Skip cache update for this PR on error - preserve previous value
-					continue
-				}
+// rateLimitFor returns the quota of the host serving the repo with key.
+func (d *Daemon) rateLimitFor(key string) github.RateLimit {
+	repo, ok := d.repoByKey(key)
+	if !ok {
+		return github.RateLimit{}
+	}
+	return d.gh(repo).RateLimit()
+}
 
-				for _, t := range threads {
-					hasCopilotInThread := false
-					for _, c := range t.Comments {
-						if isCopilotAuthor(c.Author) {
-							hasCopilotInThread = true
-							break
-						}
-					}
-					if hasCopilotInThread && !t.IsResolved && !t.IsOutdated {
-						hasUnresolvedCopilot = true
-					}
-				}
-			}
+func (d *Daemon) pollRepo(ctx context.Context, repo config.RepoConfig) error {
+	listed, err := d.gh(repo).ListOpenPRs(ctx, repo.Owner, repo.Name, repo.MaxPRs)
+	if err != nil {
+		return fmt.Errorf("list PRs: %w", err)
+	}
 
-			copilotStatuses = append(copilotStatuses, copilotStatus{
-				prKey:                prKey,
-				hasCopilotReview:     hasCopilotReview,
-				hasUnresolvedCopilot: hasUnresolvedCopilot,
-			})
+	// Only PRs targeting a configured base branch are ours to work on
+	prs := make([]github.PRInfo, 0, len(listed))
+	for _, pr := range listed {
+		if _, ok := repo.ForBase(pr.BaseRef); ok {
+			github.MarkRequired(pr.Checks, d.requiredChecks(ctx, repo, pr.BaseRef))
+			prs = append(prs, pr)
+		} else {
+			d.logger.Debug("skipping PR for unconfigured base", "repo", repo.Owner+"/"+repo.Name, "pr", pr.Number, "base", pr.BaseRef)
 		}
 	}
 
-	// Update cache with lock held only for writes
+	// Cache PR data for TUI snapshot, with lock held only for writes
 	d.prCacheMu.Lock()
-	d.prCache[repoKey] = prs
-	for _, status := range copilotStatuses {
-		d.copilotReviewCache[status.prKey] = status.hasCopilotReview
-		d.copilotUnresolvedCache[status.prKey] = status.hasUnresolvedCopilot
-	}
+	d.prCache[repoKey(repo)] = prs
 	d.prCacheMu.Unlock()
 
-	d.logger.Info("polled repo", "repo", repoKey, "open_prs", len(prs))
+	d.logger.Info("polled repo", "repo", repo.Owner+"/"+repo.Name, "host", repo.Host, "open_prs", len(prs))
 
 	// Track which PRs are still open
 	openKeys := make(map[string]bool)
@@ -193,14 +236,18 @@ func (d *Daemon) pollRepo(ctx context.Context, repo config.RepoConfig) error {
 	activeCount := d.countActiveForRepo(repo)
 
 	for _, pr := range prs {
-		key := workerKey(repo.Owner, repo.Name, pr.Number)
+		key := workerKey(repo, pr.Number)
 		openKeys[key] = true
 
+		d.handleCommands(ctx, repo, pr)
+
 		// Skip excluded authors
 		if isExcluded(pr.Author.Login, repo.ExcludeAuthors) {
 			continue
 		}
 
+		d.syncStateLabels(ctx, repo, pr)
+
 		// Skip drafts at poll level
 		if pr.IsDraft {
 			continue
@@ -211,6 +258,11 @@ func (d *Daemon) pollRepo(ctx context.Context, repo config.RepoConfig) error {
 			continue
 		}
 
+		// Paused with a comment command
+		if d.state.Get(key).Paused {
+			continue
+		}
+
 		d.mu.Lock()
 		_, running := d.workers[key]
 		d.mu.Unlock()
@@ -224,35 +276,60 @@ func (d *Daemon) pollRepo(ctx context.Context, repo config.RepoConfig) error {
 			break
 		}
 
-		d.startWorker(ctx, repo, pr)
+		prRepo, _ := repo.ForBase(pr.BaseRef)
+		d.startWorker(ctx, prRepo, pr)
 		activeCount++
 	}
 
 	// Cancel workers for PRs no longer open
 	d.mu.Lock()
-	prefix := repo.Owner + "/" + repo.Name + "#"
+	prefix := repoKey(repo) + "#"
 	for key, cancel := range d.workers {
 		if len(key) > len(prefix) && key[:len(prefix)] == prefix {
 			if !openKeys[key] {
-				d.logger.Info("PR closed externally, cancelling worker", "key", key)
+				d.logger.Info("PR closed or retargeted, cancelling worker", "key", key)
 				cancel()
 				delete(d.workers, key)
 			}
 		}
 	}
+	for key := range d.memory {
+		if strings.HasPrefix(key, prefix) && !openKeys[key] {
+			delete(d.memory, key)
+		}
+	}
 	d.mu.Unlock()
 
+	// Keep the state of every listed PR, including retargeted ones, which
+	// may come back. A listing capped by max_prs may leave open PRs out, so
+	// nothing is pruned then.
+	listedKeys := make(map[string]bool, len(listed))
+	for _, pr := range listed {
+		listedKeys[workerKey(repo, pr.Number)] = true
+	}
+	capped := repo.MaxPRs > 0 && len(listed) >= repo.MaxPRs
+	if err := d.state.Prune(func(key string) bool {
+		return capped || !strings.HasPrefix(key, prefix) || listedKeys[key]
+	}); err != nil {
+		d.logger.Error("failed to save state", "repo", repo.Owner+"/"+repo.Name, "err", err)
+	}
+
 	return nil
 }
 
 func (d *Daemon) startWorker(ctx context.Context, repo config.RepoConfig, pr github.PRInfo) {
-	key := workerKey(repo.Owner, repo.Name, pr.Number)
+	key := workerKey(repo, pr.Number)
 	workerCtx, cancel := context.WithCancel(ctx)
 
+	memory := d.memoryFor(key)
 	d.mu.Lock()
 	d.workers[key] = cancel
 	d.mu.Unlock()
 
+	if method := d.state.Get(key).MergeMethod; method != "" {
+		repo.MergeMethod = method
+	}
+
 	repoFullName := repo.Owner + "/" + repo.Name
 	onClaudeStart := func(action string) {
 		d.trackClaudeStart(key, repoFullName, pr.Number, action)
@@ -264,7 +341,9 @@ func (d *Daemon) startWorker(ctx context.Context, repo config.RepoConfig, pr git
 		d.trackClaudeOutput(key, line)
 	}
 
-	w := worker.New(repo, pr, d.gh, d.claude, d.git, d.logger, onClaudeStart, onClaudeEnd, onClaudeOutput)
+	required := d.requiredChecks(ctx, repo, pr.BaseRef)
+	reviewers := d.humanReviewers(ctx, repo)
+	w := worker.New(repo, pr, required, reviewers, memory, d.gh(repo), d.claude, d.git, d.logger, onClaudeStart, onClaudeEnd, onClaudeOutput)
 
 	d.wg.Add(1)
 	go func() {
@@ -296,7 +375,7 @@ func (d *Daemon) cancelAll() {
 func (d *Daemon) countActiveForRepo(repo config.RepoConfig) int {
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	prefix := repo.Owner + "/" + repo.Name + "#"
+	prefix := repoKey(repo) + "#"
 	count := 0
 	for key := range d.workers {
 		if len(key) > len(prefix) && key[:len(prefix)] == prefix {
@@ -306,8 +385,15 @@ func (d *Daemon) countActiveForRepo(repo config.RepoConfig) int {
 	return count
 }
 
-func workerKey(owner, repo string, number int) string {
-	return fmt.Sprintf("%s/%s#%d", owner, repo, number)
+// repoKey identifies a repo across hosts: host/owner/name.
+func repoKey(repo config.RepoConfig) string {
+	return repo.Host + "/" + repo.Owner + "/" + repo.Name
+}
+
+// workerKey identifies a PR: host/owner/name#number. It keys workers, PR
+// memory and the state store.
+func workerKey(repo config.RepoConfig, number int) string {
+	return fmt.Sprintf("%s#%d", repoKey(repo), number)
 }
 
 func isExcluded(author string, excluded []string) bool {
@@ -385,6 +471,10 @@ func (d *Daemon) GetSnapshot() tui.Snapshot {
 		workersCopy[k] = true
 	}
 	workerCount := len(d.workers)
+	memoryCopy := make(map[string]*worker.Memory, len(d.memory))
+	for k, m := range d.memory {
+		memoryCopy[k] = m
+	}
 	d.mu.Unlock()
 
 	d.sessionsMu.Lock()
@@ -410,20 +500,11 @@ func (d *Daemon) GetSnapshot() tui.Snapshot {
 	for k, v := range d.prCache {
 		prCacheCopy[k] = append([]github.PRInfo(nil), v...)
 	}
-	copilotCacheCopy := make(map[string]bool, len(d.copilotReviewCache))
-	for k, v := range d.copilotReviewCache {
-		copilotCacheCopy[k] = v
-	}
-	copilotUnresolvedCacheCopy := make(map[string]bool, len(d.copilotUnresolvedCache))
-	for k, v := range d.copilotUnresolvedCache {
-		copilotUnresolvedCacheCopy[k] = v
-	}
 	d.prCacheMu.Unlock()
 
 	repos := make([]tui.RepoState, 0, len(d.cfg.Repos))
 	for _, repo := range d.cfg.Repos {
-		repoKey := repo.Owner + "/" + repo.Name
-		prs, ok := prCacheCopy[repoKey]
+		prs, ok := prCacheCopy[repoKey(repo)]
 		if !ok {
 			prs = []github.PRInfo{}
 		}
@@ -432,7 +513,7 @@ func (d *Daemon) GetSnapshot() tui.Snapshot {
 		repoWorkers := 0
 		blockedCount := 0
 		for _, pr := range prs {
-			wk := workerKey(repo.Owner, repo.Name, pr.Number)
+			wk := workerKey(repo, pr.Number)
 			hasWorker := workersCopy[wk]
 			if hasWorker {
 				repoWorkers++
@@ -444,18 +525,20 @@ func (d *Daemon) GetSnapshot() tui.Snapshot {
 				continue
 			}
 
-			hasCopilotReview := copilotCacheCopy[wk]
-			hasUnresolvedCopilot := copilotUnresolvedCacheCopy[wk]
+			prRepo, _ := repo.ForBase(pr.BaseRef)
+			states := d.prStates(prRepo, pr, memoryCopy[wk])
 			prStates = append(prStates, tui.PRState{
-				Number:    pr.Number,
-				Title:     pr.Title,
-				States:    inferStatesFromPR(pr, *repo.RequireCopilotReview && !isRenovateAuthor(pr.Author.Login), hasCopilotReview, hasUnresolvedCopilot),
-				Author:    pr.Author.Login,
-				HasWorker: hasWorker,
+				Number:        pr.Number,
+				Title:         pr.Title,
+				States:        states,
+				Author:        pr.Author.Login,
+				HasWorker:     hasWorker,
+				QueuePosition: queuePosition(pr),
 			})
 		}
 
 		repos = append(repos, tui.RepoState{
+			Host:       repo.Host,
 			Owner:      repo.Owner,
 			Name:       repo.Name,
 			PRs:        prStates,
@@ -464,11 +547,19 @@ func (d *Daemon) GetSnapshot() tui.Snapshot {
 		})
 	}
 
+	limit := d.rateLimit()
+
 	return tui.Snapshot{
 		Timestamp:      time.Now(),
 		Repos:          repos,
 		ClaudeSessions: sessions,
 		WorkerCount:    workerCount,
+		RateLimit: tui.RateLimitState{
+			Resource:  limit.Resource,
+			Remaining: limit.Remaining,
+			Limit:     limit.Limit,
+			Reset:     limit.Reset,
+		},
 	}
 }
 
@@ -486,74 +577,122 @@ func hasBlockingLabel(pr github.PRInfo) bool {
 	return false
 }
 
-var copilotAuthors = map[string]bool{
-	"Copilot":                       true,
-	"copilot":                       true,
-	"github-copilot[bot]":           true,
-	"copilot-pull-request-reviewer": true,
-}
-
-var renovateAuthors = map[string]bool{
-	"renovate":      true,
-	"renovate[bot]": true,
-	"renovate-bot":  true,
-	"app/renovate":  true,
-}
-
-func isCopilotAuthor(author string) bool {
-	return copilotAuthors[author]
-}
-
-func isRenovateAuthor(author string) bool {
-	return renovateAuthors[author]
+// queuePosition is the PR's 1-based merge queue position, 0 when not queued.
+func queuePosition(pr github.PRInfo) int {
+	if pr.MergeQueueEntry == nil {
+		return 0
+	}
+	return pr.MergeQueueEntry.Position + 1
 }
 
-func inferStatesFromPR(pr github.PRInfo, requireCopilot bool, hasCopilotReview bool, hasUnresolvedCopilot bool) []string {
+// inferStatesFromPR derives the TUI states of a PR. memory holds what its
+// workers learned, e.g. failure categories; it may be nil.
+func inferStatesFromPR(pr github.PRInfo, repo config.RepoConfig, memory *worker.Memory) []string {
 	var states []string
 
 	if pr.IsDraft {
 		return []string{"draft"}
 	}
 
+	if pr.MergeQueueEntry != nil {
+		return []string{"merge_queued"}
+	}
+
 	if pr.Mergeable == "CONFLICTING" {
 		states = append(states, "conflicting")
 	}
 
-	hasFailingChecks := false
 	hasPendingChecks := false
+	hasOptionalFailing := false
+	checkActions := make(map[string]bool) // key: config.Check* action
+	var failingCategories []string
 	for _, c := range pr.Checks {
-		if c.Conclusion == "failure" {
-			hasFailingChecks = true
+		if repo.IsStatusCheck(c.Name) {
+			continue // auto-claude's own
 		}
-		if c.Conclusion == "" && c.Status != "COMPLETED" {
+		action := worker.CheckAction(repo, memory, pr.HeadSHA, c)
+		switch {
+		case !c.Required && !repo.FixOptionalChecks:
+			// Reported, but the worker neither fixes nor waits for these
+			if c.Completed() && action != config.CheckIgnore {
+				hasOptionalFailing = true
+			}
+		case c.Completed():
+			checkActions[action] = true
+			if category := memory.Category(pr.HeadSHA, c); action == config.CheckFailing && category != "" && !slices.Contains(failingCategories, category) {
+				failingCategories = append(failingCategories, category)
+			}
+		default:
 			hasPendingChecks = true
 		}
 	}
 
-	if hasFailingChecks {
+	// Categories show once a worker has classified the failures
+	switch {
+	case len(failingCategories) > 0:
+		for _, category := range failingCategories {
+			states = append(states, "checks_failing:"+category)
+		}
+	case checkActions[config.CheckFailing]:
 		states = append(states, "checks_failing")
 	}
+	if checkActions[config.CheckRerun] {
+		states = append(states, "checks_rerun")
+	}
 	if hasPendingChecks {
 		states = append(states, "checks_pending")
 	}
+	if checkActions[config.CheckBlock] {
+		states = append(states, "checks_blocked")
+	}
 
-	// Check for Copilot review status if required
-	if requireCopilot {
-		if !hasCopilotReview {
-			states = append(states, "copilot_pending")
-		} else if hasUnresolvedCopilot {
-			states = append(states, "fixing_reviews")
+	// Required bot reviews, as gated by the worker (Renovate is exempt)
+	reviewsPending := false
+	if !config.IsRenovateLogin(pr.Author.Login) {
+		for _, bot := range repo.ReviewBots {
+			if !bot.Required {
+				continue
+			}
+			switch worker.BotReviewStatus(bot, pr) {
+			case worker.ReviewUnresolved:
+				if bot.AutoFix {
+					states = append(states, "fixing_reviews:"+bot.Name)
+				} else {
+					reviewsPending = true
+				}
+			case worker.ReviewAwaitingHuman:
+				states = append(states, "awaiting_human:"+bot.Name)
+			case worker.ReviewMissing:
+				states = append(states, "bot_review_pending:"+bot.Name)
+			}
 		}
 	}
 
-	// Use ReviewDecision (consistent with worker merge gating in worker.go:290)
+	// Use ReviewDecision (consistent with worker merge gating in worker.go)
 	// instead of MergeStateStatus which can be BLOCKED for non-review reasons
-	if pr.ReviewDecision != "" && pr.ReviewDecision != "APPROVED" {
+	if reviewsPending || (pr.ReviewDecision != "" && pr.ReviewDecision != "APPROVED") {
 		states = append(states, "reviews_pending")
 	}
 
 	if len(states) == 0 {
-		states = append(states, "ready")
+		switch {
+		case pr.AutoMergeEnabled:
+			states = append(states, "auto_merge_pending")
+		case pr.Dequeued != nil:
+			states = append(states, "dequeued")
+		default:
+			states = append(states, "ready")
+		}
+	}
+
+	// Workers wait for a human until the PR moves on or /auto-claude retry
+	if limit := *repo.MaxFixAttempts; limit > 0 && memory.Attempts() >= limit {
+		states = append(states, "gave_up")
+	}
+
+	// Informational only, so it doesn't stand in the way of "ready"
+	if hasOptionalFailing {
+		states = append(states, "optional_checks_failing")
 	}
 
 	return states
@@ -0,0 +1,332 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marcin-skalski/auto-claude/internal/claude"
+	"github.com/marcin-skalski/auto-claude/internal/config"
+	"github.com/marcin-skalski/auto-claude/internal/fake"
+	"github.com/marcin-skalski/auto-claude/internal/github"
+	"github.com/marcin-skalski/auto-claude/internal/state"
+	"github.com/marcin-skalski/auto-claude/internal/webhook"
+	"github.com/marcin-skalski/auto-claude/internal/worker"
+)
+
+const testConfig = `repos:
+  - owner: o
+    name: r
+    require_copilot_review: false
+    review_bots:
+      - name: coderabbit
+        logins: ["coderabbitai[bot]"]
+        required: true
+        auto_fix: true
+`
+
+// updatePR changes PR #1 of o/r in gh.
+func updatePR(t *testing.T, gh *fake.GitHub, update func(pr *github.PRInfo)) {
+	t.Helper()
+	pr, err := gh.GetPRDetail(context.Background(), "o", "r", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	update(pr)
+	gh.AddPR("o", "r", *pr)
+}
+
+func command(id, body string) func(pr *github.PRInfo) {
+	return func(pr *github.PRInfo) {
+		pr.Comments = append(pr.Comments, github.Comment{ID: id, Author: "maintainer", Body: body, CreatedAt: time.Now()})
+	}
+}
+
+func TestPollRepo(t *testing.T) {
+	type poll struct {
+		before func(pr *github.PRInfo)
+
+		// Totals over all polls so far
+		wantClaudeRuns int
+		wantPushes     int
+		wantMerges     int
+		wantReruns     int
+		wantReactions  int
+		wantPaused     bool
+	}
+	tests := []struct {
+		name    string
+		pr      func(pr *github.PRInfo)
+		threads []github.ReviewThread
+		output  string // Claude's reply
+		polls   []poll
+	}{
+		{
+			name: "conflict is resolved, then the PR merges",
+			pr:   func(pr *github.PRInfo) { pr.Mergeable = "CONFLICTING" },
+			polls: []poll{
+				{wantClaudeRuns: 1, wantPushes: 1},
+				{before: func(pr *github.PRInfo) { pr.Mergeable = "MERGEABLE" }, wantClaudeRuns: 1, wantPushes: 1, wantMerges: 1},
+			},
+		},
+		{
+			name: "failing check is re-run, then fixed, then the PR merges",
+			pr: func(pr *github.PRInfo) {
+				pr.Checks[0].Conclusion = github.ConclusionFailure
+			},
+			polls: []poll{
+				{wantReruns: 1},
+				{
+					before: func(pr *github.PRInfo) {
+						pr.Checks[0].Status = "COMPLETED"
+						pr.Checks[0].Conclusion = github.ConclusionFailure
+					},
+					wantReruns: 1, wantClaudeRuns: 1, wantPushes: 1,
+				},
+				{
+					before: func(pr *github.PRInfo) {
+						pr.HeadSHA = "def"
+						pr.Checks[0].Conclusion = github.ConclusionSuccess
+					},
+					wantReruns: 1, wantClaudeRuns: 1, wantPushes: 1, wantMerges: 1,
+				},
+			},
+		},
+		{
+			name: "bot review thread is fixed, then the PR merges",
+			threads: []github.ReviewThread{{ID: "T1", Path: "main.go", Comments: []github.ReviewComment{
+				{Author: "coderabbitai[bot]", Body: "Rename x."},
+			}}},
+			output: "```json\n{\"threads\": [{\"id\": \"T1\", \"outcome\": \"fixed\", \"rationale\": \"Renamed it.\"}]}\n```",
+			polls: []poll{
+				{wantClaudeRuns: 1, wantPushes: 1},
+				// The bot doesn't review the fix; its earlier review counts
+				{before: func(pr *github.PRInfo) { pr.HeadSHA = "def" }, wantClaudeRuns: 1, wantPushes: 1, wantMerges: 1},
+			},
+		},
+		{
+			name: "paused PR waits for resume",
+			pr:   command("c1", "/auto-claude pause"),
+			polls: []poll{
+				{wantReactions: 1, wantPaused: true},
+				// The command isn't run again
+				{wantReactions: 1, wantPaused: true},
+				{before: command("c2", "/auto-claude resume"), wantReactions: 2, wantMerges: 1},
+			},
+		},
+		{
+			name: "retargeted PR keeps its pause",
+			pr:   command("c1", "/auto-claude pause"),
+			polls: []poll{
+				{wantReactions: 1, wantPaused: true},
+				{before: func(pr *github.PRInfo) { pr.BaseRef = "release" }, wantReactions: 1, wantPaused: true},
+				{before: func(pr *github.PRInfo) { pr.BaseRef = "main" }, wantReactions: 1, wantPaused: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.yaml")
+			if err := os.WriteFile(path, []byte(testConfig), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := config.Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			st, err := state.Open(filepath.Join(dir, "state.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pr := github.PRInfo{
+				Number:           1,
+				Title:            "Add feature",
+				HeadRef:          "feature",
+				HeadSHA:          "abc",
+				BaseRef:          "main",
+				Author:           github.Author{Login: "dev"},
+				Mergeable:        "MERGEABLE",
+				MergeStateStatus: "CLEAN",
+				Checks: []github.Check{
+					{Name: "test", Status: "COMPLETED", Conclusion: github.ConclusionSuccess, ID: 7, App: "github-actions"},
+				},
+			}
+			if tt.pr != nil {
+				tt.pr(&pr)
+			}
+			gh := fake.NewGitHub()
+			gh.AddPR("o", "r", pr)
+			gh.SetReviews("o", "r", 1, []github.Review{
+				{ID: "R1", Author: "coderabbitai[bot]", State: "COMMENTED", CommitSHA: "abc", SubmittedAt: time.Now()},
+			})
+			gh.SetReviewThreads("o", "r", 1, tt.threads)
+			gh.SetJobLogs(7, "--- FAIL: TestX (0.00s)\nFAIL\n")
+			gh.SetPermission("o", "r", "maintainer", "write")
+			g := fake.NewGit(filepath.Join(dir, "work"))
+			agent := fake.NewAgent()
+			agent.Result = &claude.Result{Success: true, Output: tt.output}
+			agent.OnRun = func(call fake.AgentCall) { g.Commit(call.Workdir) }
+
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			d := New(cfg, func(config.RepoConfig) worker.GitHub { return gh }, agent, g, st, logger)
+
+			for i, p := range tt.polls {
+				if p.before != nil {
+					updatePR(t, gh, p.before)
+				}
+				if err := d.pollRepo(context.Background(), cfg.Repos[0]); err != nil {
+					t.Fatalf("poll %d: %v", i+1, err)
+				}
+				d.wg.Wait()
+
+				if got := len(agent.Calls()); got != p.wantClaudeRuns {
+					t.Errorf("poll %d: Claude runs = %d, want %d", i+1, got, p.wantClaudeRuns)
+				}
+				if got := len(g.Pushes()); got != p.wantPushes {
+					t.Errorf("poll %d: pushes = %d, want %d", i+1, got, p.wantPushes)
+				}
+				if got := len(gh.Merges()); got != p.wantMerges {
+					t.Errorf("poll %d: merges = %d, want %d", i+1, got, p.wantMerges)
+				}
+				if got := len(gh.Reruns()); got != p.wantReruns {
+					t.Errorf("poll %d: reruns = %d, want %d", i+1, got, p.wantReruns)
+				}
+				if got := len(gh.Reactions()); got != p.wantReactions {
+					t.Errorf("poll %d: reactions = %d, want %d", i+1, got, p.wantReactions)
+				}
+				if got := st.Get(workerKey(cfg.Repos[0], 1)).Paused; got != p.wantPaused {
+					t.Errorf("poll %d: paused = %v, want %v", i+1, got, p.wantPaused)
+				}
+			}
+		})
+	}
+}
+
+func TestPollReposWithSameNameOnTwoHosts(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	yaml := "repos:\n" +
+		"  - {owner: o, name: r, require_copilot_review: false}\n" +
+		"  - {host: ghe.example.com, owner: o, name: r, require_copilot_review: false}\n"
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := state.Open(filepath.Join(dir, "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hosts := map[string]*fake.GitHub{}
+	for _, repo := range cfg.Repos {
+		gh := fake.NewGitHub()
+		pr := github.PRInfo{
+			Number: 1, Title: "Add feature", HeadRef: "feature", HeadSHA: "abc", BaseRef: "main",
+			Author: github.Author{Login: "dev"}, Mergeable: "MERGEABLE", MergeStateStatus: "CLEAN",
+		}
+		if repo.Host != "github.com" {
+			command("c1", "/auto-claude pause")(&pr)
+			gh.SetPermission("o", "r", "maintainer", "write")
+		}
+		gh.AddPR("o", "r", pr)
+		hosts[repo.Host] = gh
+	}
+	g := fake.NewGit(filepath.Join(dir, "work"))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	d := New(cfg, func(repo config.RepoConfig) worker.GitHub { return hosts[repo.Host] }, fake.NewAgent(), g, st, logger)
+
+	for _, repo := range cfg.Repos {
+		if err := d.pollRepo(context.Background(), repo); err != nil {
+			t.Fatalf("poll %s: %v", repo.Host, err)
+		}
+	}
+	d.wg.Wait()
+
+	dotcom, ghes := cfg.Repos[0], cfg.Repos[1]
+	if got := len(hosts[dotcom.Host].Merges()); got != 1 {
+		t.Errorf("github.com merges = %d, want 1", got)
+	}
+	if got := len(hosts[ghes.Host].Merges()); got != 0 {
+		t.Errorf("%s merges = %d, want 0 while paused", ghes.Host, got)
+	}
+	if st.Get(workerKey(dotcom, 1)).Paused || !st.Get(workerKey(ghes, 1)).Paused {
+		t.Errorf("paused = %v on github.com and %v on %s, want only the latter",
+			st.Get(workerKey(dotcom, 1)).Paused, st.Get(workerKey(ghes, 1)).Paused, ghes.Host)
+	}
+}
+
+func TestNewMigratesStateKeysWithoutHost(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(testConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statePath := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(statePath, []byte(`{"o/r#1": {"paused": true}, "gone/repo#2": {"paused": true}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	st, err := state.Open(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	New(cfg, nil, nil, nil, st, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if !st.Get("github.com/o/r#1").Paused {
+		t.Error("state of o/r#1 wasn't moved to github.com/o/r#1")
+	}
+	if !st.Get("gone/repo#2").Paused {
+		t.Error("state of an unconfigured repo was dropped")
+	}
+}
+
+func TestWebhookTriggersCollapsePerRepo(t *testing.T) {
+	cfg := &config.Config{}
+	for i := range 100 {
+		cfg.Repos = append(cfg.Repos, config.RepoConfig{Host: "github.com", Owner: "o", Name: fmt.Sprintf("r%d", i)})
+	}
+	st, err := state.Open(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := New(cfg, nil, nil, nil, st, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// More repos than the old trigger queue held, each delivered twice
+	for range 2 {
+		for _, repo := range cfg.Repos {
+			d.handleWebhookEvent(webhook.Event{Host: "github.com", Type: "push", Owner: repo.Owner, Repo: repo.Name})
+		}
+	}
+
+	select {
+	case <-d.triggers:
+	default:
+		t.Fatal("no poll was signalled")
+	}
+	keys := d.takeTriggered()
+	if len(keys) != len(cfg.Repos) {
+		t.Fatalf("triggered %d repos, want each of the %d once", len(keys), len(cfg.Repos))
+	}
+	for i, repo := range cfg.Repos {
+		if keys[i] != repoKey(repo) {
+			t.Errorf("triggered[%d] = %s, want %s", i, keys[i], repoKey(repo))
+		}
+	}
+	if keys := d.takeTriggered(); len(keys) != 0 {
+		t.Errorf("triggered %v after taking them all", keys)
+	}
+}
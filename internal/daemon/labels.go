@@ -0,0 +1,159 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/marcin-skalski/auto-claude/internal/config"
+	"github.com/marcin-skalski/auto-claude/internal/github"
+	"github.com/marcin-skalski/auto-claude/internal/state"
+	"github.com/marcin-skalski/auto-claude/internal/webhook"
+	"github.com/marcin-skalski/auto-claude/internal/worker"
+)
+
+// prStates returns the states of a PR as shown in the TUI and mirrored onto
+// state labels. repo is the config in effect for the PR's base.
+func (d *Daemon) prStates(repo config.RepoConfig, pr github.PRInfo, memory *worker.Memory) []string {
+	if d.state.Get(workerKey(repo, pr.Number)).Paused {
+		return []string{"paused"}
+	}
+	return inferStatesFromPR(pr, repo, memory)
+}
+
+// labelState maps a TUI state to its state_labels key, e.g.
+// "checks_failing:flaky" to "checks-failing".
+func labelState(state string) string {
+	state, _, _ = strings.Cut(state, ":")
+	return strings.ReplaceAll(state, "_", "-")
+}
+
+// syncStateLabels adds the labels of the PR's current states and removes
+// those of states it left, as configured by state_labels. The labels added
+// are recorded in the state store, so those named after an earlier prefix or
+// label name are removed too. Failures are only logged; the next poll tries
+// again.
+func (d *Daemon) syncStateLabels(ctx context.Context, repo config.RepoConfig, pr github.PRInfo) {
+	repo, _ = repo.ForBase(pr.BaseRef)
+	sl := repo.StateLabels
+	if sl == nil || !sl.Enabled {
+		return
+	}
+	key := workerKey(repo, pr.Number)
+	logger := d.logger.With("key", key)
+
+	d.mu.Lock()
+	memory := d.memory[key]
+	d.mu.Unlock()
+
+	var want []config.StateLabel
+	for _, s := range d.prStates(repo, pr, memory) {
+		label, ok := sl.Labels[labelState(s)]
+		if ok && !slices.Contains(want, label) {
+			want = append(want, label)
+		}
+	}
+	wanted := func(name string) bool {
+		return slices.ContainsFunc(want, func(label config.StateLabel) bool { return label.Name == name })
+	}
+	hasLabel := func(name string) bool {
+		return slices.ContainsFunc(pr.Labels, func(l github.Label) bool { return l.Name == name })
+	}
+
+	gh := d.gh(repo)
+	var added, present []string // present: state labels on the PR after syncing
+	for _, label := range want {
+		if hasLabel(label.Name) {
+			present = append(present, label.Name)
+			continue
+		}
+		if err := d.ensureLabel(ctx, repo, label); err != nil {
+			logger.Error("failed to create state label", "label", label.Name, "err", err)
+			continue
+		}
+		added = append(added, label.Name)
+	}
+	if len(added) > 0 {
+		if err := gh.AddLabels(ctx, repo.Owner, repo.Name, pr.Number, added); err != nil {
+			logger.Error("failed to add state labels", "labels", added, "err", err)
+		} else {
+			logger.Info("added state labels", "labels", added)
+			present = append(present, added...)
+		}
+	}
+
+	recorded := d.state.Get(key).Labels
+	for _, l := range pr.Labels {
+		if wanted(l.Name) || !sl.IsStateLabel(l.Name) && !slices.Contains(recorded, l.Name) {
+			continue
+		}
+		if err := gh.RemoveLabel(ctx, repo.Owner, repo.Name, pr.Number, l.Name); err != nil {
+			logger.Error("failed to remove state label", "label", l.Name, "err", err)
+			present = append(present, l.Name)
+			continue
+		}
+		logger.Info("removed state label", "label", l.Name)
+	}
+
+	slices.Sort(present)
+	if !slices.Equal(present, recorded) {
+		if err := d.state.Update(key, func(s *state.PR) { s.Labels = present }); err != nil {
+			logger.Error("failed to save state", "err", err)
+		}
+	}
+}
+
+// isStateLabelEvent reports whether a webhook event only added or removed a
+// state label. Those are auto-claude's own changes, or undone by it on the
+// next poll, so they don't need a poll of their own.
+func (d *Daemon) isStateLabelEvent(repo config.RepoConfig, evt webhook.Event) bool {
+	if evt.Type != "pull_request" || evt.Action != "labeled" && evt.Action != "unlabeled" || evt.Label == "" {
+		return false
+	}
+	if sl := repo.StateLabels; sl != nil && sl.Enabled && sl.IsStateLabel(evt.Label) {
+		return true
+	}
+	for _, number := range evt.PRNumbers {
+		if slices.Contains(d.state.Get(workerKey(repo, number)).Labels, evt.Label) {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureLabel creates a state label missing from the repo, or updates its
+// colour and description to the configured ones. Each label is checked once
+// per daemon process.
+func (d *Daemon) ensureLabel(ctx context.Context, repo config.RepoConfig, label config.StateLabel) error {
+	cacheKey := repoKey(repo) + "@" + label.Name
+	d.labelsMu.Lock()
+	ok := d.labels[cacheKey]
+	d.labelsMu.Unlock()
+	if ok {
+		return nil
+	}
+
+	gh := d.gh(repo)
+	want := github.Label{Name: label.Name, Color: label.Color, Description: label.Description}
+	existing, err := gh.GetLabel(ctx, repo.Owner, repo.Name, label.Name)
+	var notFound *github.NotFoundError
+	switch {
+	case errors.As(err, &notFound):
+		if err := gh.CreateLabel(ctx, repo.Owner, repo.Name, want); err != nil {
+			return err
+		}
+		d.logger.Info("created state label", "repo", repo.Owner+"/"+repo.Name, "label", label.Name)
+	case err != nil:
+		return err
+	case !strings.EqualFold(existing.Color, want.Color) || existing.Description != want.Description:
+		if err := gh.UpdateLabel(ctx, repo.Owner, repo.Name, want); err != nil {
+			return err
+		}
+	}
+
+	d.labelsMu.Lock()
+	d.labels[cacheKey] = true
+	d.labelsMu.Unlock()
+	return nil
+}
@@ -0,0 +1,30 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/marcin-skalski/auto-claude/internal/config"
+	"github.com/marcin-skalski/auto-claude/internal/github"
+)
+
+// requiredChecksTTL is how long a base branch's required checks are cached.
+// Protection rules change rarely; this bounds the extra API calls per poll.
+const requiredChecksTTL = 10 * time.Minute
+
+// requiredChecks returns the checks branch protection and rulesets require
+// to merge into base. Unknown ones, e.g. because protection can't be read,
+// make every check count as required.
+func (d *Daemon) requiredChecks(ctx context.Context, repo config.RepoConfig, base string) github.RequiredChecks {
+	required, err := d.required.get(repoKey(repo)+"@"+base, func() (github.RequiredChecks, error) {
+		required, err := d.gh(repo).GetRequiredChecks(ctx, repo.Owner, repo.Name, base)
+		if err == nil && !required.Known {
+			d.logger.Warn("can't read branch protection or rulesets, treating every check as required", "repo", repo.Owner+"/"+repo.Name, "base", base)
+		}
+		return required, err
+	})
+	if err != nil {
+		d.logger.Warn("failed to get required checks", "repo", repo.Owner+"/"+repo.Name, "base", base, "err", err)
+	}
+	return required
+}
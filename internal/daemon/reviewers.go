@@ -0,0 +1,41 @@
+package daemon
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/marcin-skalski/auto-claude/internal/config"
+)
+
+// teamMembersTTL is how long a team's members are cached.
+const teamMembersTTL = 10 * time.Minute
+
+// humanReviewers returns the logins whose review feedback Claude addresses
+// on the repo: human_reviews.reviewers plus the members of its teams. Empty
+// when human_reviews is off.
+func (d *Daemon) humanReviewers(ctx context.Context, repo config.RepoConfig) []string {
+	if repo.HumanReviews == nil {
+		return nil
+	}
+	reviewers := slices.Clone(repo.HumanReviews.Reviewers)
+	for _, team := range repo.HumanReviews.Teams {
+		org, slug, ok := strings.Cut(team, "/")
+		if !ok {
+			org, slug = repo.Owner, team
+		}
+		reviewers = append(reviewers, d.teamMembers(ctx, repo, org, slug)...)
+	}
+	return reviewers
+}
+
+func (d *Daemon) teamMembers(ctx context.Context, repo config.RepoConfig, org, team string) []string {
+	logins, err := d.teams.get(repo.Host+"/"+org+"/"+team, func() ([]string, error) {
+		return d.gh(repo).ListTeamMembers(ctx, org, team)
+	})
+	if err != nil {
+		d.logger.Warn("failed to list team members", "team", org+"/"+team, "err", err)
+	}
+	return logins
+}
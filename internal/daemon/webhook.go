@@ -0,0 +1,126 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/marcin-skalski/auto-claude/internal/config"
+	"github.com/marcin-skalski/auto-claude/internal/webhook"
+)
+
+// startWebhookServer listens for GitHub webhook deliveries until ctx is done.
+func (d *Daemon) startWebhookServer(ctx context.Context) error {
+	secret := os.Getenv(d.cfg.Webhook.SecretEnv)
+	if secret == "" {
+		return fmt.Errorf("webhook enabled but %s is empty", d.cfg.Webhook.SecretEnv)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(d.cfg.Webhook.Path, webhook.NewHandler([]byte(secret), d.handleWebhookEvent, d.logger))
+
+	ln, err := net.Listen("tcp", d.cfg.Webhook.Listen)
+	if err != nil {
+		return fmt.Errorf("webhook listen: %w", err)
+	}
+
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			d.logger.Error("webhook server failed", "err", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	d.logger.Info("webhook server listening", "addr", ln.Addr().String(), "path", d.cfg.Webhook.Path)
+	return nil
+}
+
+// handleWebhookEvent schedules an immediate poll of the event's repo. Several
+// deliveries for the same repo before the poll runs collapse into one.
+func (d *Daemon) handleWebhookEvent(evt webhook.Event) {
+	repo, ok := d.findRepo(evt.Host, evt.Owner, evt.Repo)
+	if !ok {
+		d.logger.Debug("webhook for unmonitored repo", "repo", evt.Owner+"/"+evt.Repo, "host", evt.Host)
+		return
+	}
+	key := repoKey(repo)
+	if d.isStateLabelEvent(repo, evt) {
+		d.logger.Debug("ignoring state label webhook", "repo", key, "action", evt.Action, "label", evt.Label)
+		return
+	}
+
+	d.triggerMu.Lock()
+	queued := slices.Contains(d.triggered, key)
+	if !queued {
+		d.triggered = append(d.triggered, key)
+	}
+	d.triggerMu.Unlock()
+	if queued {
+		return
+	}
+
+	d.logger.Info("webhook triggered poll", "repo", key, "event", evt.Type, "action", evt.Action, "prs", evt.PRNumbers)
+
+	select {
+	case d.triggers <- struct{}{}:
+	default:
+		// Already signalled; the run loop takes every queued repo at once
+	}
+}
+
+// takeTriggered returns the repos queued by handleWebhookEvent, oldest
+// first, and empties the queue. Deliveries during their polls queue them
+// again.
+func (d *Daemon) takeTriggered() []string {
+	d.triggerMu.Lock()
+	defer d.triggerMu.Unlock()
+	keys := d.triggered
+	d.triggered = nil
+	return keys
+}
+
+// pollTriggered polls a repo scheduled by handleWebhookEvent.
+func (d *Daemon) pollTriggered(ctx context.Context, key string) {
+	repo, ok := d.repoByKey(key)
+	if !ok {
+		return
+	}
+	if err := d.pollRepo(ctx, repo); err != nil {
+		d.logger.Error("poll repo failed", "repo", key, "err", err)
+	}
+}
+
+func (d *Daemon) findRepo(host, owner, name string) (config.RepoConfig, bool) {
+	for _, repo := range d.cfg.Repos {
+		if strings.EqualFold(repo.Host, host) && strings.EqualFold(repo.Owner, owner) && strings.EqualFold(repo.Name, name) {
+			return repo, true
+		}
+	}
+	return config.RepoConfig{}, false
+}
+
+// repoByKey returns the configured repo with the given repoKey.
+func (d *Daemon) repoByKey(key string) (config.RepoConfig, bool) {
+	for _, repo := range d.cfg.Repos {
+		if repoKey(repo) == key {
+			return repo, true
+		}
+	}
+	return config.RepoConfig{}, false
+}
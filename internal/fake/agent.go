@@ -0,0 +1,64 @@
+package fake
+
+import (
+	"context"
+	"sync"
+
+	"github.com/marcin-skalski/auto-claude/internal/claude"
+)
+
+// AgentCall records one agent invocation.
+type AgentCall struct {
+	Workdir string
+	Prompt  string
+}
+
+// Agent is an in-memory worker.Agent that never spawns a process.
+type Agent struct {
+	mu    sync.Mutex
+	calls []AgentCall
+
+	// Result is returned from every run; nil means a successful empty result.
+	Result *claude.Result
+	// Err is returned from every run alongside Result.
+	Err error
+	// OnRun is called for every run, e.g. to simulate commits with Git.Commit.
+	OnRun func(call AgentCall)
+}
+
+func NewAgent() *Agent {
+	return &Agent{}
+}
+
+// Calls returns all recorded invocations in order.
+func (a *Agent) Calls() []AgentCall {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]AgentCall(nil), a.calls...)
+}
+
+func (a *Agent) RunWithCallback(_ context.Context, workdir, prompt string, callback claude.OutputCallback) (*claude.Result, error) {
+	return a.run(AgentCall{Workdir: workdir, Prompt: prompt}, callback)
+}
+
+func (a *Agent) run(call AgentCall, callback claude.OutputCallback) (*claude.Result, error) {
+	a.mu.Lock()
+	a.calls = append(a.calls, call)
+	onRun := a.OnRun
+	result := a.Result
+	err := a.Err
+	a.mu.Unlock()
+
+	if onRun != nil {
+		onRun(call)
+	}
+
+	if result == nil {
+		result = &claude.Result{Success: true}
+	}
+	if callback != nil && result.Output != "" {
+		callback(result.Output)
+	}
+	r := *result
+	return &r, err
+}
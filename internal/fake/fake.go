@@ -0,0 +1,9 @@
+package fake
+
+import "github.com/marcin-skalski/auto-claude/internal/worker"
+
+var (
+	_ worker.GitHub = (*GitHub)(nil)
+	_ worker.Agent  = (*Agent)(nil)
+	_ worker.Git    = (*Git)(nil)
+)
@@ -0,0 +1,118 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Git is an in-memory worker.Git. Worktrees are real (empty) directories under
+// the root passed to NewGit, since workers write agent logs into them.
+type Git struct {
+	root string
+
+	mu       sync.Mutex
+	unpushed map[string][]string // key: worktree dir, value: commit SHAs
+	commits  int
+	pushes   []string
+	errs     map[string]error // key: method name
+}
+
+func NewGit(root string) *Git {
+	return &Git{
+		root:     root,
+		unpushed: make(map[string][]string),
+		errs:     make(map[string]error),
+	}
+}
+
+// Commit adds a local commit to dir that Push has not sent yet, and returns
+// its made-up SHA.
+func (g *Git) Commit(dir string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.commits++
+	sha := fmt.Sprintf("%040x", g.commits)
+	g.unpushed[dir] = append(g.unpushed[dir], sha)
+	return sha
+}
+
+// Pushes returns the branches pushed in order.
+func (g *Git) Pushes() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.pushes...)
+}
+
+// SetError makes every call to the named method fail with err until cleared
+// with a nil err.
+func (g *Git) SetError(method string, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err == nil {
+		delete(g.errs, method)
+		return
+	}
+	g.errs[method] = err
+}
+
+func (g *Git) EnsureClone(_ context.Context, _, _, _ string) error {
+	return g.err("EnsureClone")
+}
+
+func (g *Git) AddWorktree(_ context.Context, host, owner, repo, _ string, prNumber int) (string, error) {
+	if err := g.err("AddWorktree"); err != nil {
+		return "", err
+	}
+	dir := filepath.Join(g.root, host, owner+"-"+repo, fmt.Sprintf("pr-%d", prNumber))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("mkdir worktree: %w", err)
+	}
+	return dir, nil
+}
+
+func (g *Git) RemoveWorktree(_ context.Context, host, owner, repo string, prNumber int) error {
+	dir := filepath.Join(g.root, host, owner+"-"+repo, fmt.Sprintf("pr-%d", prNumber))
+	return os.RemoveAll(dir)
+}
+
+func (g *Git) Fetch(_ context.Context, _ string) error {
+	return g.err("Fetch")
+}
+
+func (g *Git) Push(_ context.Context, dir, branch string) error {
+	if err := g.err("Push"); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.unpushed, dir)
+	g.pushes = append(g.pushes, branch)
+	return nil
+}
+
+func (g *Git) HasUnpushedCommits(_ context.Context, dir, _ string) (bool, error) {
+	if err := g.err("HasUnpushedCommits"); err != nil {
+		return false, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.unpushed[dir]) > 0, nil
+}
+
+func (g *Git) UnpushedCommits(_ context.Context, dir, _ string) ([]string, error) {
+	if err := g.err("UnpushedCommits"); err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.unpushed[dir]...), nil
+}
+
+func (g *Git) err(method string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.errs[method]
+}
@@ -0,0 +1,781 @@
+// Package fake provides in-memory implementations of the GitHub, agent and git
+// dependencies of the daemon and workers, so the PR state machine can be
+// exercised in tests without gh, claude or git binaries.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/marcin-skalski/auto-claude/internal/github"
+)
+
+// GitHub is an in-memory worker.GitHub. The zero value is not usable; create
+// one with NewGitHub. All methods are safe for concurrent use.
+type GitHub struct {
+	mu          sync.Mutex
+	prs         map[string][]github.PRInfo       // key: owner/repo
+	reviews     map[string][]github.Review       // key: owner/repo#number
+	threads     map[string][]github.ReviewThread // key: owner/repo#number
+	commits     map[string][]github.Commit       // key: owner/repo#number
+	jobLogs     map[int64]string                 // key: job ID
+	annotations map[int64][]github.Annotation    // key: check run ID
+	teams       map[string][]string              // key: org/team
+	permissions map[string]string                // key: owner/repo@login
+	required    map[string][]string              // key: owner/repo@branch
+	labels      map[string]github.Label          // key: owner/repo@name
+	errs        map[string]error                 // key: method name
+	rate        github.RateLimit
+
+	merges          []Merge
+	resolvedThreads []string
+	updatedBranches []string
+	reruns          []string
+	reviewRequests  []string
+	reactions       []string
+	checkRuns       []github.CheckRun // index: ID-1
+	statuses        []github.CommitStatus
+	nextComment     int
+}
+
+// Merge records a MergePR call.
+type Merge struct {
+	Owner   string
+	Repo    string
+	Number  int
+	Options github.MergeOptions
+}
+
+func NewGitHub() *GitHub {
+	return &GitHub{
+		prs:         make(map[string][]github.PRInfo),
+		reviews:     make(map[string][]github.Review),
+		threads:     make(map[string][]github.ReviewThread),
+		commits:     make(map[string][]github.Commit),
+		jobLogs:     make(map[int64]string),
+		annotations: make(map[int64][]github.Annotation),
+		teams:       make(map[string][]string),
+		permissions: make(map[string]string),
+		required:    make(map[string][]string),
+		labels:      make(map[string]github.Label),
+		errs:        make(map[string]error),
+	}
+}
+
+// AddPR adds or replaces an open PR.
+func (g *GitHub) AddPR(owner, repo string, pr github.PRInfo) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := owner + "/" + repo
+	pr = clonePR(pr)
+	for i, existing := range g.prs[key] {
+		if existing.Number == pr.Number {
+			g.prs[key][i] = pr
+			return
+		}
+	}
+	g.prs[key] = append(g.prs[key], pr)
+}
+
+// SetReviews replaces the reviews of a PR.
+func (g *GitHub) SetReviews(owner, repo string, number int, reviews []github.Review) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.reviews[prKey(owner, repo, number)] = reviews
+}
+
+// SetReviewThreads replaces the review threads of a PR.
+func (g *GitHub) SetReviewThreads(owner, repo string, number int, threads []github.ReviewThread) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.threads[prKey(owner, repo, number)] = threads
+}
+
+// SetCommits replaces the commits of a PR.
+func (g *GitHub) SetCommits(owner, repo string, number int, commits []github.Commit) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.commits[prKey(owner, repo, number)] = commits
+}
+
+// SetRequiredChecks sets the checks required to merge into branch; none
+// means no check is required.
+func (g *GitHub) SetRequiredChecks(owner, repo, branch string, names []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.required[owner+"/"+repo+"@"+branch] = names
+}
+
+// SetJobLogs sets the log returned for an Actions job.
+func (g *GitHub) SetJobLogs(jobID int64, log string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.jobLogs[jobID] = log
+}
+
+// SetCheckAnnotations sets the annotations returned for a check run.
+func (g *GitHub) SetCheckAnnotations(checkID int64, annotations []github.Annotation) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.annotations[checkID] = annotations
+}
+
+// SetTeamMembers sets the member logins of an org team.
+func (g *GitHub) SetTeamMembers(org, team string, logins []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.teams[org+"/"+team] = logins
+}
+
+// SetPermission sets a user's permission on a repo; users without one have
+// none.
+func (g *GitHub) SetPermission(owner, repo, login, permission string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.permissions[owner+"/"+repo+"@"+login] = permission
+}
+
+// SetError makes every call to the named method (e.g. "MergePR") fail with
+// err until it is cleared with a nil err.
+func (g *GitHub) SetError(method string, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err == nil {
+		delete(g.errs, method)
+		return
+	}
+	g.errs[method] = err
+}
+
+// SetRateLimit sets the quota RateLimit reports.
+func (g *GitHub) SetRateLimit(limit github.RateLimit) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.rate = limit
+}
+
+// Merges returns the successful MergePR calls in order.
+func (g *GitHub) Merges() []Merge {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Merge(nil), g.merges...)
+}
+
+// ResolvedThreads returns the IDs passed to ResolveReviewThread in order.
+func (g *GitHub) ResolvedThreads() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.resolvedThreads...)
+}
+
+// Reruns returns the names of re-run checks in order.
+func (g *GitHub) Reruns() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.reruns...)
+}
+
+// ReviewRequests returns "owner/repo#number login" for each bot passed to
+// RequestBotReviews.
+func (g *GitHub) ReviewRequests() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.reviewRequests...)
+}
+
+// UpdatedBranches returns owner/repo#number keys passed to UpdateBranch and
+// RebaseBranch.
+func (g *GitHub) UpdatedBranches() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.updatedBranches...)
+}
+
+// Reactions returns "subjectID:content" for each AddReaction call in order.
+func (g *GitHub) Reactions() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.reactions...)
+}
+
+// CheckRuns returns the check runs created, as last updated, in order of
+// creation. A run's ID is its index plus one.
+func (g *GitHub) CheckRuns() []github.CheckRun {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]github.CheckRun(nil), g.checkRuns...)
+}
+
+// Statuses returns the commit statuses set in order.
+func (g *GitHub) Statuses() []github.CommitStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]github.CommitStatus(nil), g.statuses...)
+}
+
+// SetLabel adds or replaces a repo label.
+func (g *GitHub) SetLabel(owner, repo string, label github.Label) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.labels[owner+"/"+repo+"@"+label.Name] = label
+}
+
+// Label returns a repo label, as created or last updated.
+func (g *GitHub) Label(owner, repo, name string) (github.Label, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	label, ok := g.labels[owner+"/"+repo+"@"+name]
+	return label, ok
+}
+
+// Comments returns the bodies of the comments posted on a PR, as last
+// edited.
+func (g *GitHub) Comments(owner, repo string, number int) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	pr, _ := g.findPR(owner, repo, number)
+	var bodies []string
+	for _, c := range pr.Comments {
+		bodies = append(bodies, c.Body)
+	}
+	return bodies
+}
+
+func (g *GitHub) ListOpenPRs(_ context.Context, owner, repo string, limit int) ([]github.PRInfo, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["ListOpenPRs"]; err != nil {
+		return nil, err
+	}
+	prs := make([]github.PRInfo, 0, len(g.prs[owner+"/"+repo]))
+	for _, pr := range g.prs[owner+"/"+repo] {
+		if limit > 0 && len(prs) >= limit {
+			break
+		}
+		prs = append(prs, g.withReviews(owner, repo, pr))
+	}
+	return prs, nil
+}
+
+func (g *GitHub) GetPRDetail(_ context.Context, owner, repo string, number int) (*github.PRInfo, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["GetPRDetail"]; err != nil {
+		return nil, err
+	}
+	pr, ok := g.findPR(owner, repo, number)
+	if !ok {
+		return nil, notFound("get PR #%d", number)
+	}
+	pr = g.withReviews(owner, repo, pr)
+	return &pr, nil
+}
+
+func (g *GitHub) GetReviews(_ context.Context, owner, repo string, number int) ([]github.Review, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["GetReviews"]; err != nil {
+		return nil, err
+	}
+	return append([]github.Review{}, g.reviews[prKey(owner, repo, number)]...), nil
+}
+
+func (g *GitHub) GetReviewThreads(_ context.Context, owner, repo string, number int) ([]github.ReviewThread, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["GetReviewThreads"]; err != nil {
+		return nil, err
+	}
+	return cloneThreads(g.threads[prKey(owner, repo, number)]), nil
+}
+
+func (g *GitHub) ResolveReviewThread(_ context.Context, threadID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["ResolveReviewThread"]; err != nil {
+		return err
+	}
+	for key, threads := range g.threads {
+		for i := range threads {
+			if threads[i].ID == threadID {
+				g.threads[key][i].IsResolved = true
+			}
+		}
+	}
+	g.resolvedThreads = append(g.resolvedThreads, threadID)
+	return nil
+}
+
+// AddThreadReply appends a comment by "auto-claude" to the thread.
+func (g *GitHub) AddThreadReply(_ context.Context, threadID, body string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["AddThreadReply"]; err != nil {
+		return err
+	}
+	for key, threads := range g.threads {
+		for i := range threads {
+			if threads[i].ID == threadID {
+				reply := github.ReviewComment{Author: "auto-claude", Body: body}
+				g.threads[key][i].Comments = append(g.threads[key][i].Comments, reply)
+				return nil
+			}
+		}
+	}
+	return notFound("thread %s", threadID)
+}
+
+func (g *GitHub) ListTeamMembers(_ context.Context, org, team string) ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["ListTeamMembers"]; err != nil {
+		return nil, err
+	}
+	members, ok := g.teams[org+"/"+team]
+	if !ok {
+		return nil, notFound("team %s/%s", org, team)
+	}
+	return append([]string(nil), members...), nil
+}
+
+func (g *GitHub) UpdateBranch(_ context.Context, owner, repo string, number int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["UpdateBranch"]; err != nil {
+		return err
+	}
+	g.updatedBranches = append(g.updatedBranches, prKey(owner, repo, number))
+	return nil
+}
+
+// RebaseBranch records the PR like UpdateBranch, with a "rebase:" prefix.
+func (g *GitHub) RebaseBranch(_ context.Context, owner, repo string, number int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["RebaseBranch"]; err != nil {
+		return err
+	}
+	g.updatedBranches = append(g.updatedBranches, "rebase:"+prKey(owner, repo, number))
+	return nil
+}
+
+func (g *GitHub) GetLabel(_ context.Context, owner, repo, name string) (*github.Label, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["GetLabel"]; err != nil {
+		return nil, err
+	}
+	label, ok := g.labels[owner+"/"+repo+"@"+name]
+	if !ok {
+		return nil, notFound("label %q", name)
+	}
+	return &label, nil
+}
+
+func (g *GitHub) CreateLabel(_ context.Context, owner, repo string, label github.Label) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["CreateLabel"]; err != nil {
+		return err
+	}
+	key := owner + "/" + repo + "@" + label.Name
+	if _, ok := g.labels[key]; ok {
+		return fmt.Errorf("create label %q: already exists", label.Name)
+	}
+	g.labels[key] = label
+	return nil
+}
+
+func (g *GitHub) UpdateLabel(_ context.Context, owner, repo string, label github.Label) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["UpdateLabel"]; err != nil {
+		return err
+	}
+	key := owner + "/" + repo + "@" + label.Name
+	if _, ok := g.labels[key]; !ok {
+		return notFound("update label %q", label.Name)
+	}
+	g.labels[key] = label
+	return nil
+}
+
+// AddLabels adds the labels to the PR, which must exist in the repo.
+func (g *GitHub) AddLabels(_ context.Context, owner, repo string, number int, names []string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["AddLabels"]; err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, ok := g.labels[owner+"/"+repo+"@"+name]; !ok {
+			return notFound("add labels to PR #%d: label %q", number, name)
+		}
+	}
+	return g.updatePR(owner, repo, number, func(pr *github.PRInfo) {
+		for _, name := range names {
+			if !slices.ContainsFunc(pr.Labels, func(l github.Label) bool { return l.Name == name }) {
+				pr.Labels = append(pr.Labels, github.Label{Name: name})
+			}
+		}
+	})
+}
+
+func (g *GitHub) RemoveLabel(_ context.Context, owner, repo string, number int, name string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["RemoveLabel"]; err != nil {
+		return err
+	}
+	return g.updatePR(owner, repo, number, func(pr *github.PRInfo) {
+		pr.Labels = slices.DeleteFunc(pr.Labels, func(l github.Label) bool { return l.Name == name })
+	})
+}
+
+// AddReaction records the reaction as "subjectID:content" and marks the
+// comment as reacted to.
+func (g *GitHub) AddReaction(_ context.Context, subjectID, content string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["AddReaction"]; err != nil {
+		return err
+	}
+	g.reactions = append(g.reactions, subjectID+":"+content)
+	for _, prs := range g.prs {
+		for i := range prs {
+			for j := range prs[i].Comments {
+				if prs[i].Comments[j].ID == subjectID {
+					prs[i].Comments[j].Reacted = true
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (g *GitHub) GetPermission(_ context.Context, owner, repo, login string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["GetPermission"]; err != nil {
+		return "", err
+	}
+	if permission, ok := g.permissions[owner+"/"+repo+"@"+login]; ok {
+		return permission, nil
+	}
+	return "none", nil
+}
+
+func (g *GitHub) PostComment(_ context.Context, owner, repo string, number int, body string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["PostComment"]; err != nil {
+		return err
+	}
+	g.nextComment++
+	comment := github.Comment{
+		ID:        fmt.Sprintf("comment-%d", g.nextComment),
+		Author:    "auto-claude",
+		Body:      body,
+		CreatedAt: time.Now(),
+		ByViewer:  true,
+	}
+	return g.updatePR(owner, repo, number, func(pr *github.PRInfo) {
+		pr.Comments = append(pr.Comments, comment)
+	})
+}
+
+func (g *GitHub) GetComments(_ context.Context, owner, repo string, number int) ([]github.Comment, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["GetComments"]; err != nil {
+		return nil, err
+	}
+	pr, ok := g.findPR(owner, repo, number)
+	if !ok {
+		return nil, notFound("get comments of PR #%d", number)
+	}
+	return slices.Clone(pr.Comments), nil
+}
+
+func (g *GitHub) GetCommentsSince(_ context.Context, owner, repo string, number int, since time.Time) ([]github.Comment, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["GetCommentsSince"]; err != nil {
+		return nil, err
+	}
+	pr, ok := g.findPR(owner, repo, number)
+	if !ok {
+		return nil, notFound("get comments of PR #%d", number)
+	}
+	var comments []github.Comment
+	for _, c := range pr.Comments {
+		if !c.CreatedAt.Before(since) {
+			comments = append(comments, c)
+		}
+	}
+	return comments, nil
+}
+
+func (g *GitHub) UpdateComment(_ context.Context, commentID, body string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["UpdateComment"]; err != nil {
+		return err
+	}
+	for _, prs := range g.prs {
+		for i := range prs {
+			for j := range prs[i].Comments {
+				if prs[i].Comments[j].ID == commentID {
+					if !prs[i].Comments[j].ByViewer {
+						return &github.AuthError{APIError: github.APIError{StatusCode: 403, Message: "update comment " + commentID + ": Forbidden"}}
+					}
+					prs[i].Comments[j].Body = body
+					return nil
+				}
+			}
+		}
+	}
+	return notFound("comment %s", commentID)
+}
+
+// GetRequiredChecks returns the checks set with SetRequiredChecks, unknown
+// for branches without any.
+func (g *GitHub) GetRequiredChecks(_ context.Context, owner, repo, branch string) (github.RequiredChecks, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["GetRequiredChecks"]; err != nil {
+		return github.RequiredChecks{}, err
+	}
+	names, ok := g.required[owner+"/"+repo+"@"+branch]
+	return github.RequiredChecks{Names: slices.Clone(names), Known: ok}, nil
+}
+
+// RerunCheck records the re-run and marks the check as queued.
+func (g *GitHub) RerunCheck(_ context.Context, owner, repo string, check github.Check) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["RerunCheck"]; err != nil {
+		return err
+	}
+	g.reruns = append(g.reruns, check.Name)
+	key := owner + "/" + repo
+	for i := range g.prs[key] {
+		for j, c := range g.prs[key][i].Checks {
+			if c.ID == check.ID && c.Name == check.Name {
+				g.prs[key][i].Checks[j].Status = "QUEUED"
+				g.prs[key][i].Checks[j].Conclusion = ""
+			}
+		}
+	}
+	return nil
+}
+
+func (g *GitHub) CreateCheckRun(_ context.Context, _, _ string, run github.CheckRun) (int64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["CreateCheckRun"]; err != nil {
+		return 0, err
+	}
+	g.checkRuns = append(g.checkRuns, run)
+	return int64(len(g.checkRuns)), nil
+}
+
+func (g *GitHub) UpdateCheckRun(_ context.Context, _, _ string, id int64, run github.CheckRun) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["UpdateCheckRun"]; err != nil {
+		return err
+	}
+	if id < 1 || id > int64(len(g.checkRuns)) {
+		return notFound("check run %d", id)
+	}
+	run.HeadSHA = g.checkRuns[id-1].HeadSHA
+	g.checkRuns[id-1] = run
+	return nil
+}
+
+func (g *GitHub) SetCommitStatus(_ context.Context, _, _, _ string, status github.CommitStatus) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["SetCommitStatus"]; err != nil {
+		return err
+	}
+	g.statuses = append(g.statuses, status)
+	return nil
+}
+
+func (g *GitHub) GetJobLogs(_ context.Context, _, _ string, jobID int64) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["GetJobLogs"]; err != nil {
+		return "", err
+	}
+	log, ok := g.jobLogs[jobID]
+	if !ok {
+		return "", notFound("logs of job %d", jobID)
+	}
+	return log, nil
+}
+
+func (g *GitHub) GetCheckAnnotations(_ context.Context, _, _ string, checkID int64) ([]github.Annotation, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["GetCheckAnnotations"]; err != nil {
+		return nil, err
+	}
+	return slices.Clone(g.annotations[checkID]), nil
+}
+
+func (g *GitHub) GetPRCommits(_ context.Context, owner, repo string, number int) ([]github.Commit, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["GetPRCommits"]; err != nil {
+		return nil, err
+	}
+	return append([]github.Commit(nil), g.commits[prKey(owner, repo, number)]...), nil
+}
+
+// MergePR records the merge and removes the PR from the open list.
+func (g *GitHub) MergePR(_ context.Context, owner, repo string, number int, opts github.MergeOptions) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["MergePR"]; err != nil {
+		return err
+	}
+	key := owner + "/" + repo
+	for i, pr := range g.prs[key] {
+		if pr.Number == number {
+			g.prs[key] = append(g.prs[key][:i], g.prs[key][i+1:]...)
+			g.merges = append(g.merges, Merge{Owner: owner, Repo: repo, Number: number, Options: opts})
+			return nil
+		}
+	}
+	return notFound("merge PR #%d", number)
+}
+
+// EnableAutoMerge marks the PR as having auto-merge enabled. Unlike GitHub it
+// never merges it; call MergePR to simulate that.
+func (g *GitHub) EnableAutoMerge(_ context.Context, owner, repo string, number int, _ github.MergeOptions) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["EnableAutoMerge"]; err != nil {
+		return err
+	}
+	return g.updatePR(owner, repo, number, func(pr *github.PRInfo) {
+		pr.AutoMergeEnabled = true
+	})
+}
+
+// RequestBotReviews adds the bots to the PR's requested reviewers.
+func (g *GitHub) RequestBotReviews(_ context.Context, owner, repo string, number int, bots []string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["RequestBotReviews"]; err != nil {
+		return err
+	}
+	for _, bot := range bots {
+		g.reviewRequests = append(g.reviewRequests, prKey(owner, repo, number)+" "+bot)
+	}
+	return g.updatePR(owner, repo, number, func(pr *github.PRInfo) {
+		for _, bot := range bots {
+			if !slices.Contains(pr.RequestedReviews, bot) {
+				pr.RequestedReviews = append(pr.RequestedReviews, bot)
+			}
+		}
+	})
+}
+
+// EnqueuePR puts the PR at the back of its repo's merge queue.
+func (g *GitHub) EnqueuePR(_ context.Context, owner, repo string, number int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.errs["EnqueuePR"]; err != nil {
+		return err
+	}
+	queued := 0
+	for _, pr := range g.prs[owner+"/"+repo] {
+		if pr.MergeQueueEntry != nil {
+			queued++
+		}
+	}
+	return g.updatePR(owner, repo, number, func(pr *github.PRInfo) {
+		pr.MergeQueueEntry = &github.MergeQueueEntry{Position: queued, State: "QUEUED"}
+		pr.Dequeued = nil
+	})
+}
+
+func (g *GitHub) RateLimit() github.RateLimit {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.rate
+}
+
+func (g *GitHub) findPR(owner, repo string, number int) (github.PRInfo, bool) {
+	for _, pr := range g.prs[owner+"/"+repo] {
+		if pr.Number == number {
+			return pr, true
+		}
+	}
+	return github.PRInfo{}, false
+}
+
+func (g *GitHub) updatePR(owner, repo string, number int, update func(*github.PRInfo)) error {
+	key := owner + "/" + repo
+	for i := range g.prs[key] {
+		if g.prs[key][i].Number == number {
+			update(&g.prs[key][i])
+			return nil
+		}
+	}
+	return notFound("PR #%d", number)
+}
+
+// withReviews attaches the stored reviews and threads, as the real client's
+// batched query does.
+// The PR is a deep copy, so later changes to the fake don't show through.
+func (g *GitHub) withReviews(owner, repo string, pr github.PRInfo) github.PRInfo {
+	key := prKey(owner, repo, pr.Number)
+	pr.Reviews = append([]github.Review{}, g.reviews[key]...)
+	pr.ReviewThreads = cloneThreads(g.threads[key])
+	return clonePR(pr)
+}
+
+// clonePR deep-copies the slices and pointers of a PR.
+func clonePR(pr github.PRInfo) github.PRInfo {
+	pr.Labels = slices.Clone(pr.Labels)
+	pr.Checks = slices.Clone(pr.Checks)
+	pr.Reviews = slices.Clone(pr.Reviews)
+	pr.ReviewThreads = cloneThreads(pr.ReviewThreads)
+	pr.RequestedReviews = slices.Clone(pr.RequestedReviews)
+	pr.Comments = slices.Clone(pr.Comments)
+	if pr.MergeQueueEntry != nil {
+		entry := *pr.MergeQueueEntry
+		pr.MergeQueueEntry = &entry
+	}
+	if pr.Dequeued != nil {
+		dequeued := *pr.Dequeued
+		pr.Dequeued = &dequeued
+	}
+	return pr
+}
+
+func cloneThreads(threads []github.ReviewThread) []github.ReviewThread {
+	cloned := make([]github.ReviewThread, 0, len(threads))
+	for _, t := range threads {
+		t.Comments = slices.Clone(t.Comments)
+		cloned = append(cloned, t)
+	}
+	return cloned
+}
+
+// notFound returns the error the real client reports for missing resources.
+func notFound(format string, args ...any) error {
+	return &github.NotFoundError{APIError: github.APIError{StatusCode: 404, Message: fmt.Sprintf(format, args...) + ": Not Found"}}
+}
+
+func prKey(owner, repo string, number int) string {
+	return fmt.Sprintf("%s/%s#%d", owner, repo, number)
+}
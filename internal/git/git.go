@@ -4,53 +4,66 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 )
 
+// TokenFunc returns the token git authenticates with for owner's repos on host.
+type TokenFunc func(ctx context.Context, host, owner string) (string, error)
+
+// tokenEnv passes the token to credentialHelper without putting it on the
+// command line.
+const tokenEnv = "AUTO_CLAUDE_GIT_TOKEN"
+
+const credentialHelper = `!f() { test "$1" = get && echo username=x-access-token && echo "password=$` + tokenEnv + `"; }; f`
+
 type Client struct {
 	workdir string
+	tokens  TokenFunc
 	logger  *slog.Logger
 }
 
-func NewClient(workdir string, logger *slog.Logger) *Client {
-	return &Client{workdir: workdir, logger: logger}
+// NewClient creates a git client. With a nil tokens, remote operations use
+// the user's own git credentials.
+func NewClient(workdir string, tokens TokenFunc, logger *slog.Logger) *Client {
+	return &Client{workdir: workdir, tokens: tokens, logger: logger}
 }
 
-// CloneDir returns the bare clone directory for a repo.
-func (c *Client) CloneDir(owner, repo string) string {
-	return filepath.Join(c.workdir, "clones", owner+"-"+repo)
+// CloneDir returns the bare clone directory for a repo on host.
+func (c *Client) CloneDir(host, owner, repo string) string {
+	return filepath.Join(c.workdir, "clones", host, owner+"-"+repo)
 }
 
 // WorktreeDir returns the worktree directory for a specific PR.
-func (c *Client) WorktreeDir(owner, repo string, prNumber int) string {
-	return filepath.Join(c.workdir, "worktrees", owner+"-"+repo, fmt.Sprintf("pr-%d", prNumber))
+func (c *Client) WorktreeDir(host, owner, repo string, prNumber int) string {
+	return filepath.Join(c.workdir, "worktrees", host, owner+"-"+repo, fmt.Sprintf("pr-%d", prNumber))
 }
 
-// EnsureClone clones the repo if missing, fetches if exists.
-func (c *Client) EnsureClone(ctx context.Context, owner, repo string) error {
-	dir := c.CloneDir(owner, repo)
+// EnsureClone clones the repo from host if missing, fetches if exists.
+func (c *Client) EnsureClone(ctx context.Context, host, owner, repo string) error {
+	dir := c.CloneDir(host, owner, repo)
 
 	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
 		c.logger.Debug("fetching existing clone", "dir", dir)
-		return c.run(ctx, dir, "git", "fetch", "--all", "--prune")
+		return c.runRemote(ctx, dir, host, owner, "fetch", "--all", "--prune")
 	}
 
 	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
 		return fmt.Errorf("mkdir: %w", err)
 	}
 
-	url := fmt.Sprintf("https://github.com/%s/%s.git", owner, repo)
-	c.logger.Info("cloning repo", "url", url, "dir", dir)
-	return c.run(ctx, "", "git", "clone", url, dir)
+	cloneURL := fmt.Sprintf("https://%s/%s/%s.git", host, owner, repo)
+	c.logger.Info("cloning repo", "url", cloneURL, "dir", dir)
+	return c.runRemote(ctx, "", host, owner, "clone", cloneURL, dir)
 }
 
 // AddWorktree creates a worktree for the given branch.
-func (c *Client) AddWorktree(ctx context.Context, owner, repo, branch string, prNumber int) (string, error) {
-	cloneDir := c.CloneDir(owner, repo)
-	wtDir := c.WorktreeDir(owner, repo, prNumber)
+func (c *Client) AddWorktree(ctx context.Context, host, owner, repo, branch string, prNumber int) (string, error) {
+	cloneDir := c.CloneDir(host, owner, repo)
+	wtDir := c.WorktreeDir(host, owner, repo, prNumber)
 
 	if err := os.MkdirAll(filepath.Dir(wtDir), 0o755); err != nil {
 		return "", fmt.Errorf("mkdir worktree parent: %w", err)
@@ -84,9 +97,9 @@ func (c *Client) AddWorktree(ctx context.Context, owner, repo, branch string, pr
 }
 
 // RemoveWorktree removes a worktree.
-func (c *Client) RemoveWorktree(ctx context.Context, owner, repo string, prNumber int) error {
-	cloneDir := c.CloneDir(owner, repo)
-	wtDir := c.WorktreeDir(owner, repo, prNumber)
+func (c *Client) RemoveWorktree(ctx context.Context, host, owner, repo string, prNumber int) error {
+	cloneDir := c.CloneDir(host, owner, repo)
+	wtDir := c.WorktreeDir(host, owner, repo, prNumber)
 
 	c.logger.Debug("removing worktree", "dir", wtDir)
 	if err := c.run(ctx, cloneDir, "git", "worktree", "remove", "--force", wtDir); err != nil {
@@ -98,12 +111,55 @@ func (c *Client) RemoveWorktree(ctx context.Context, owner, repo string, prNumbe
 
 // Fetch fetches all remotes in the given directory.
 func (c *Client) Fetch(ctx context.Context, dir string) error {
-	return c.run(ctx, dir, "git", "fetch", "origin")
+	host, owner, err := c.origin(ctx, dir)
+	if err != nil {
+		return err
+	}
+	return c.runRemote(ctx, dir, host, owner, "fetch", "origin")
 }
 
 // Push pushes the branch to the remote.
 func (c *Client) Push(ctx context.Context, dir, branch string) error {
-	return c.run(ctx, dir, "git", "push", "origin", branch)
+	host, owner, err := c.origin(ctx, dir)
+	if err != nil {
+		return err
+	}
+	return c.runRemote(ctx, dir, host, owner, "push", "origin", branch)
+}
+
+// origin returns the host and owner of dir's origin remote, which EnsureClone
+// sets to https://<host>/<owner>/<repo>.git.
+func (c *Client) origin(ctx context.Context, dir string) (host, owner string, err error) {
+	if c.tokens == nil {
+		return "", "", nil
+	}
+	cmd := exec.CommandContext(ctx, "git", "remote", "get-url", "origin")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", "", fmt.Errorf("git remote get-url origin: %w", err)
+	}
+	u, err := url.Parse(strings.TrimSpace(string(out)))
+	if err != nil {
+		return "", "", fmt.Errorf("parse origin URL: %w", err)
+	}
+	owner, _, _ = strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	return u.Host, owner, nil
+}
+
+// runRemote runs a git command that talks to the remote, authenticating with
+// the token for host and owner when a TokenFunc is set.
+func (c *Client) runRemote(ctx context.Context, dir, host, owner string, args ...string) error {
+	if c.tokens == nil {
+		return c.run(ctx, dir, "git", args...)
+	}
+	token, err := c.tokens(ctx, host, owner)
+	if err != nil {
+		return fmt.Errorf("get git token for %s/%s: %w", host, owner, err)
+	}
+	// The empty helper drops inherited ones so only the token is offered
+	args = append([]string{"-c", "credential.helper=", "-c", "credential.helper=" + credentialHelper}, args...)
+	return c.runEnv(ctx, dir, []string{tokenEnv + "=" + token}, "git", args...)
 }
 
 // HasUnpushedCommits checks if there are local commits not on remote.
@@ -121,12 +177,33 @@ func (c *Client) HasUnpushedCommits(ctx context.Context, dir, branch string) (bo
 	return count != "0", nil
 }
 
+// UnpushedCommits returns the SHAs of the branch's commits that aren't on
+// the remote yet, oldest first.
+func (c *Client) UnpushedCommits(ctx context.Context, dir, branch string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "rev-list", "--reverse", "origin/"+branch+".."+branch)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		c.logger.Debug("exec", "cmd", "git rev-list --reverse origin/"+branch+".."+branch, "dir", dir)
+		return nil, fmt.Errorf("git rev-list origin/%s..%s: %w", branch, branch, err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
 func (c *Client) run(ctx context.Context, dir string, name string, args ...string) error {
+	return c.runEnv(ctx, dir, nil, name, args...)
+}
+
+// runEnv is run with extra environment variables.
+func (c *Client) runEnv(ctx context.Context, dir string, env []string, name string, args ...string) error {
 	c.logger.Debug("exec", "cmd", name+" "+strings.Join(args, " "), "dir", dir)
 	cmd := exec.CommandContext(ctx, name, args...)
 	if dir != "" {
 		cmd.Dir = dir
 	}
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("%s %s: %w\n%s", name, strings.Join(args, " "), err, string(out))
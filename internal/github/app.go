@@ -0,0 +1,155 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// tokenRefreshMargin is how long before expiry an installation token is
+// replaced. Tokens live for an hour; a long agent run shouldn't outlast one.
+const tokenRefreshMargin = 10 * time.Minute
+
+// AppAuth authenticates as a GitHub App: it signs JWTs with the app's private
+// key and exchanges them for installation tokens.
+type AppAuth struct {
+	appID string
+	key   *rsa.PrivateKey
+	api   *Client // authenticated with the app JWT
+}
+
+// NewAppAuth loads the app's PEM private key from keyPath. apiURL is the REST
+// API root of the host the app is installed on.
+func NewAppAuth(appID int64, keyPath, apiURL string, logger *slog.Logger) (*AppAuth, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("read app private key: %w", err)
+	}
+	key, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse app private key %s: %w", keyPath, err)
+	}
+
+	a := &AppAuth{appID: strconv.FormatInt(appID, 10), key: key}
+	transport, err := NewHTTPTransport(apiURL, appJWT{a}, nil)
+	if err != nil {
+		return nil, err
+	}
+	a.api = NewClient(transport, logger)
+	return a, nil
+}
+
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	// GitHub issues PKCS#1 keys; accept PKCS#8 for converted ones
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("want RSA key, got %T", parsed)
+	}
+	return key, nil
+}
+
+// jwt returns an RS256 app JWT valid for nine minutes. GitHub rejects
+// lifetimes over ten; iat is backdated to absorb clock drift.
+func (a *AppAuth) jwt() (string, error) {
+	now := time.Now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("sign app JWT: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// appJWT authenticates app-level requests.
+type appJWT struct{ app *AppAuth }
+
+func (s appJWT) Token(context.Context) (string, error) {
+	return s.app.jwt()
+}
+
+// Installation returns the token source for the app's installation on the
+// account owning owner/repo. The installation is looked up on first use.
+func (a *AppAuth) Installation(owner, repo string) *InstallationToken {
+	return &InstallationToken{app: a, owner: owner, repo: repo}
+}
+
+// InstallationToken is a TokenSource for one app installation. Tokens are
+// cached and replaced shortly before they expire.
+type InstallationToken struct {
+	app   *AppAuth
+	owner string
+	repo  string
+
+	mu      sync.Mutex
+	id      int64
+	token   string
+	expires time.Time
+}
+
+func (t *InstallationToken) Token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" && time.Until(t.expires) > tokenRefreshMargin {
+		return t.token, nil
+	}
+
+	if t.id == 0 {
+		var inst struct {
+			ID int64 `json:"id"`
+		}
+		path := fmt.Sprintf("repos/%s/%s/installation", t.owner, t.repo)
+		if err := t.app.api.rest(ctx, http.MethodGet, path, nil, &inst); err != nil {
+			return "", fmt.Errorf("find app installation for %s: %w", t.owner, err)
+		}
+		t.id = inst.ID
+	}
+
+	var resp struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	path := fmt.Sprintf("app/installations/%d/access_tokens", t.id)
+	if err := t.app.api.rest(ctx, http.MethodPost, path, nil, &resp); err != nil {
+		return "", fmt.Errorf("create installation token for %s: %w", t.owner, err)
+	}
+	t.token = resp.Token
+	t.expires = resp.ExpiresAt
+	t.app.api.logger.Debug("refreshed installation token", "owner", t.owner, "expires", t.expires)
+	return t.token, nil
+}
@@ -0,0 +1,100 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// APIError is a failed GitHub API request. Classified failures are returned as
+// one of the wrapper types below; match them with errors.As.
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int    // 0 when no HTTP error was returned (e.g. GraphQL errors)
+	Type       string // GraphQL error type, e.g. NOT_FOUND
+	Message    string
+}
+
+func (e APIError) Error() string {
+	if e.StatusCode == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s %s: HTTP %d: %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+// NotFoundError means the repo, PR or other resource doesn't exist (or isn't
+// visible with the current credentials).
+type NotFoundError struct{ APIError }
+
+// AuthError means the credentials are missing, invalid or lack permission.
+type AuthError struct{ APIError }
+
+// RateLimitError means a primary or secondary rate limit was hit. Reset is
+// when requests may resume, if known.
+type RateLimitError struct {
+	APIError
+	Reset time.Time
+}
+
+// MergeConflictError means GitHub refused a merge or branch update because
+// the PR conflicts with its base.
+type MergeConflictError struct{ APIError }
+
+// BaseModifiedError means the base branch moved while merging; updating the
+// PR branch and retrying usually succeeds.
+type BaseModifiedError struct{ APIError }
+
+// CleanStatusError means auto-merge couldn't be enabled because the PR is
+// already mergeable; merge it directly instead.
+type CleanStatusError struct{ APIError }
+
+// TransientError is a 5xx response worth retrying.
+type TransientError struct{ APIError }
+
+// NetworkError means no response was received, e.g. because the connection
+// was reset or timed out. Transports return it; idempotent requests are
+// retried.
+type NetworkError struct{ Err error }
+
+func (e *NetworkError) Error() string { return "network: " + e.Err.Error() }
+
+func (e *NetworkError) Unwrap() error { return e.Err }
+
+// classify wraps e in the matching error type.
+func classify(e APIError, resp *Response) error {
+	msg := strings.ToLower(e.Message)
+
+	switch {
+	case e.Type == "NOT_FOUND" || e.StatusCode == http.StatusNotFound:
+		return &NotFoundError{e}
+	case e.Type == "RATE_LIMITED" || resp != nil && isRateLimited(resp):
+		rl := &RateLimitError{APIError: e}
+		if resp != nil {
+			if limit, ok := parseRateLimit(resp.Header); ok && limit.Remaining == 0 {
+				rl.Reset = limit.Reset
+			}
+		}
+		return rl
+	case e.Type == "FORBIDDEN" || e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return &AuthError{e}
+	case strings.Contains(msg, "base branch was modified"):
+		return &BaseModifiedError{e}
+	case strings.Contains(msg, "clean status"):
+		return &CleanStatusError{e}
+	case strings.Contains(msg, "merge conflict"):
+		return &MergeConflictError{e}
+	case e.StatusCode >= 500:
+		return &TransientError{e}
+	default:
+		return &e
+	}
+}
+
+func isRateLimited(resp *Response) bool {
+	if isSecondaryLimit(resp) {
+		return true
+	}
+	return resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0"
+}
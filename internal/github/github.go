@@ -3,38 +3,121 @@ package github
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
-	"os/exec"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"slices"
 	"strings"
+	"time"
 )
 
 type Client struct {
-	logger *slog.Logger
+	transport Transport
+	rate      *rateTracker
+	logger    *slog.Logger
+	backoff   func(attempt int) time.Duration // delay before retry attempt+1
 }
 
-func NewClient(logger *slog.Logger) *Client {
-	return &Client{logger: logger}
+func NewClient(transport Transport, logger *slog.Logger) *Client {
+	return &Client{transport: transport, rate: newRateTracker(), logger: logger, backoff: backoff}
+}
+
+// RateLimit returns the most constrained API quota seen so far.
+func (c *Client) RateLimit() RateLimit {
+	return c.rate.lowest()
 }
 
 type PRInfo struct {
-	Number            int         `json:"number"`
-	Title             string      `json:"title"`
-	HeadRef           string      `json:"headRefName"`
-	BaseRef           string      `json:"baseRefName"`
-	URL               string      `json:"url"`
-	IsDraft           bool        `json:"isDraft"`
-	Author            Author      `json:"author"`
-	Mergeable         string      `json:"mergeable"`
-	MergeStateStatus  string      `json:"mergeStateStatus"`
-	ReviewDecision    string      `json:"reviewDecision"`
-	Labels            []Label     `json:"labels"`
-	Checks            []Check     `json:"-"`
-	StatusCheckRollup []checkNode `json:"statusCheckRollup"`
+	Number           int
+	Title            string
+	HeadRef          string
+	HeadSHA          string
+	BaseRef          string
+	URL              string
+	Body             string
+	IsDraft          bool
+	Author           Author
+	Mergeable        string
+	MergeStateStatus string
+	ReviewDecision   string
+	Labels           []Label
+	Checks           []Check
+	Reviews          []Review
+	ReviewThreads    []ReviewThread
+	RequestedReviews []string  // logins of users and bots, and slugs of teams, with a pending review request
+	Comments         []Comment // the latest conversation comments, oldest first
+	OlderComments    bool      // Comments leaves out older ones
+	AutoMergeEnabled bool
+	MergeQueueEntry  *MergeQueueEntry // nil when not queued
+	Dequeued         *DequeueEvent    // set while the latest queue or push event is a removal from the queue
+}
+
+// Commit is a commit on a PR branch.
+type Commit struct {
+	SHA         string
+	Message     string
+	AuthorName  string
+	AuthorEmail string
+	AuthorLogin string // empty when the email isn't linked to a GitHub account
+}
+
+// Annotation is a message a check run attached to its result, e.g. a
+// compiler error or a problem matcher's lint finding.
+type Annotation struct {
+	Path    string
+	Line    int
+	Level   string // notice, warning or failure
+	Title   string
+	Message string
+}
+
+// CheckRun is a check run to create or update. Conclusion is only set once
+// Status is completed.
+type CheckRun struct {
+	Name       string
+	HeadSHA    string
+	Status     string // queued | in_progress | completed
+	Conclusion string // success | failure | neutral | ...
+	Title      string
+	Summary    string // Markdown
+	DetailsURL string
+}
+
+// CommitStatus is a commit status to set.
+type CommitStatus struct {
+	Context     string
+	State       string // pending | success | failure | error
+	Description string // truncated to GitHub's 140 characters
+	TargetURL   string
+}
+
+// MergeOptions controls how a PR is merged. Empty commit fields leave
+// GitHub's defaults; they're ignored for rebase merges.
+type MergeOptions struct {
+	Method        string // squash | merge | rebase
+	CommitTitle   string
+	CommitMessage string
+}
+
+// MergeQueueEntry is a PR's place in its base branch's merge queue.
+type MergeQueueEntry struct {
+	Position int    // 0 is the head of the queue
+	State    string // QUEUED, AWAITING_CHECKS, MERGEABLE, UNMERGEABLE, LOCKED
+}
+
+// DequeueEvent is a PR's removal from the merge queue.
+type DequeueEvent struct {
+	Reason string
+	At     time.Time
 }
 
 type Label struct {
-	Name string `json:"name"`
+	Name        string `json:"name"`
+	Color       string `json:"color,omitempty"` // hex without '#'; only set by GetLabel
+	Description string `json:"description,omitempty"`
 }
 
 type Author struct {
@@ -45,14 +128,77 @@ type checkNode struct {
 	Name       string `json:"name"`
 	Status     string `json:"status"`
 	Conclusion string `json:"conclusion"`
-	Context    string `json:"context"`
-	State      string `json:"state"`
+	DatabaseID int64  `json:"databaseId"`
+	DetailsURL string `json:"detailsUrl"`
+	CheckSuite *struct {
+		App *struct {
+			Slug string `json:"slug"`
+		} `json:"app"`
+	} `json:"checkSuite"`
+	Context   string `json:"context"`
+	State     string `json:"state"`
+	TargetURL string `json:"targetUrl"`
 }
 
+// Check conclusions, as normalized by the client. Commit statuses map to
+// success or failure. A check without a conclusion hasn't completed.
+const (
+	ConclusionSuccess        = "success"
+	ConclusionFailure        = "failure"
+	ConclusionNeutral        = "neutral"
+	ConclusionSkipped        = "skipped"
+	ConclusionCancelled      = "cancelled"
+	ConclusionTimedOut       = "timed_out"
+	ConclusionActionRequired = "action_required"
+	ConclusionStartupFailure = "startup_failure"
+	ConclusionStale          = "stale"
+)
+
 type Check struct {
 	Name       string
-	Status     string
-	Conclusion string
+	Status     string // COMPLETED, or QUEUED, IN_PROGRESS, PENDING, ... while running
+	Conclusion string // one of the Conclusion constants; empty until completed
+	ID         int64  // check run ID (the job ID for GitHub Actions); 0 for commit statuses
+	DetailsURL string // where the check's results are shown
+	App        string // slug of the app that created the check run, e.g. github-actions
+	Required   bool   // required by branch protection or rulesets, see MarkRequired
+}
+
+// RequiredChecks are the checks required to merge into a branch. Known is
+// false when its protection or rulesets couldn't be read, so Names may be
+// incomplete.
+type RequiredChecks struct {
+	Names []string
+	Known bool
+}
+
+// MarkRequired sets Required on the checks named in required. When the
+// required checks aren't known, every check counts as required.
+func MarkRequired(checks []Check, required RequiredChecks) {
+	for i := range checks {
+		checks[i].Required = !required.Known || slices.Contains(required.Names, checks[i].Name)
+	}
+}
+
+// Completed reports whether the check has finished with a conclusion.
+func (c Check) Completed() bool {
+	return c.Conclusion != ""
+}
+
+// Passed reports whether the check completed without a problem.
+func (c Check) Passed() bool {
+	switch c.Conclusion {
+	case ConclusionSuccess, ConclusionNeutral, ConclusionSkipped:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsActionsJob reports whether the check is a GitHub Actions job, whose logs
+// GetJobLogs can fetch.
+func (c Check) IsActionsJob() bool {
+	return c.ID != 0 && c.App == "github-actions"
 }
 
 type ReviewThread struct {
@@ -69,71 +215,501 @@ type ReviewComment struct {
 	Body   string `json:"body"`
 }
 
+// Comment is a comment in a PR's conversation.
+type Comment struct {
+	ID        string    `json:"id"` // GraphQL node ID
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	Reacted   bool      `json:"reacted"`   // the authenticated user reacted with ReactionThumbsUp or ReactionConfused
+	ByViewer  bool      `json:"by_viewer"` // written by the authenticated user
+}
+
+// Reactions AddReaction can add.
+const (
+	ReactionThumbsUp = "THUMBS_UP"
+	ReactionConfused = "CONFUSED"
+)
+
+// LatestReview returns the most recently submitted review by an author
+// isAuthor accepts. Pending and dismissed reviews don't count.
+func LatestReview(reviews []Review, isAuthor func(login string) bool) (Review, bool) {
+	var latest Review
+	found := false
+	for _, r := range reviews {
+		if !isAuthor(r.Author) || r.State == "PENDING" || r.State == "DISMISSED" {
+			continue
+		}
+		if !found || !r.SubmittedAt.Before(latest.SubmittedAt) {
+			latest = r
+			found = true
+		}
+	}
+	return latest, found
+}
+
 type Review struct {
-	Author string `json:"author"`
-	State  string `json:"state"`
+	ID          string    `json:"id"`
+	Author      string    `json:"author"`
+	Body        string    `json:"body"`
+	State       string    `json:"state"`
+	CommitSHA   string    `json:"commit_sha"`   // head commit the review was submitted on
+	SubmittedAt time.Time `json:"submitted_at"` // zero for pending reviews
+}
+
+// prFields selects the PullRequest fields that make up a PRInfo.
+const prFields = `fragment prFields on PullRequest {
+  number
+  title
+  headRefName
+  headRefOid
+  baseRefName
+  url
+  body
+  isDraft
+  author { login }
+  mergeable
+  mergeStateStatus
+  reviewDecision
+  labels(first: 100) {
+    nodes { name }
+  }
+  reviewRequests(first: 100) {
+    nodes {
+      requestedReviewer {
+        ... on User { login }
+        ... on Bot { login }
+        ... on Team { slug }
+      }
+    }
+  }
+  comments(last: 30) {
+    pageInfo { hasPreviousPage }
+    nodes { ...commentFields }
+  }
+  autoMergeRequest { enabledAt }
+  mergeQueueEntry { position state }
+  timelineItems(last: 1, itemTypes: [ADDED_TO_MERGE_QUEUE_EVENT, REMOVED_FROM_MERGE_QUEUE_EVENT, PULL_REQUEST_COMMIT, HEAD_REF_FORCE_PUSHED_EVENT]) {
+    nodes {
+      __typename
+      ... on RemovedFromMergeQueueEvent { reason createdAt }
+    }
+  }
+  reviews(first: 50) {
+    pageInfo { hasNextPage }
+    nodes {
+      id
+      author { login }
+      body
+      state
+      submittedAt
+      commit { oid }
+    }
+  }
+  reviewThreads(first: 50) {
+    pageInfo { hasNextPage }
+    nodes {
+      id
+      isResolved
+      isOutdated
+      path
+      line
+      comments(first: 50) {
+        pageInfo {
+          hasNextPage
+          endCursor
+        }
+        nodes {
+          author { login }
+          body
+        }
+      }
+    }
+  }
+  commits(last: 1) {
+    nodes {
+      commit {
+        statusCheckRollup {
+          contexts(first: 100) {
+            pageInfo {
+              hasNextPage
+              endCursor
+            }
+            nodes { ...checkFields }
+          }
+        }
+      }
+    }
+  }
+}
+` + commentFields + "\n" + checkFields
+
+// checkFields selects the check run and commit status fields that make up a
+// Check.
+const checkFields = `fragment checkFields on StatusCheckRollupContext {
+  ... on CheckRun { name status conclusion databaseId detailsUrl checkSuite { app { slug } } }
+  ... on StatusContext { context state targetUrl }
+}`
+
+// commentFields selects the IssueComment fields that make up a Comment.
+const commentFields = `fragment commentFields on IssueComment {
+  id
+  author { login }
+  body
+  createdAt
+  viewerDidAuthor
+  reactionGroups { content viewerHasReacted }
+}`
+
+type graphQLPR struct {
+	Number           int    `json:"number"`
+	Title            string `json:"title"`
+	HeadRefName      string `json:"headRefName"`
+	HeadRefOid       string `json:"headRefOid"`
+	BaseRefName      string `json:"baseRefName"`
+	URL              string `json:"url"`
+	Body             string `json:"body"`
+	IsDraft          bool   `json:"isDraft"`
+	Author           Author `json:"author"`
+	Mergeable        string `json:"mergeable"`
+	MergeStateStatus string `json:"mergeStateStatus"`
+	ReviewDecision   string `json:"reviewDecision"`
+	Labels           struct {
+		Nodes []Label `json:"nodes"`
+	} `json:"labels"`
+	ReviewRequests struct {
+		Nodes []struct {
+			RequestedReviewer struct {
+				Login string `json:"login"`
+				Slug  string `json:"slug"`
+			} `json:"requestedReviewer"`
+		} `json:"nodes"`
+	} `json:"reviewRequests"`
+	Comments struct {
+		PageInfo pageInfo              `json:"pageInfo"`
+		Nodes    []graphQLIssueComment `json:"nodes"`
+	} `json:"comments"`
+	AutoMergeRequest *struct{}        `json:"autoMergeRequest"`
+	MergeQueueEntry  *MergeQueueEntry `json:"mergeQueueEntry"`
+	TimelineItems    struct {
+		Nodes []struct {
+			Typename  string    `json:"__typename"`
+			Reason    string    `json:"reason"`
+			CreatedAt time.Time `json:"createdAt"`
+		} `json:"nodes"`
+	} `json:"timelineItems"`
+	Reviews struct {
+		PageInfo pageInfo        `json:"pageInfo"`
+		Nodes    []graphQLReview `json:"nodes"`
+	} `json:"reviews"`
+	ReviewThreads struct {
+		PageInfo pageInfo        `json:"pageInfo"`
+		Nodes    []graphQLThread `json:"nodes"`
+	} `json:"reviewThreads"`
+	Commits struct {
+		Nodes []struct {
+			Commit struct {
+				StatusCheckRollup *struct {
+					Contexts struct {
+						PageInfo pageInfo    `json:"pageInfo"`
+						Nodes    []checkNode `json:"nodes"`
+					} `json:"contexts"`
+				} `json:"statusCheckRollup"`
+			} `json:"commit"`
+		} `json:"nodes"`
+	} `json:"commits"`
 }
 
-func (c *Client) ListOpenPRs(ctx context.Context, owner, repo string) ([]PRInfo, error) {
-	args := []string{
-		"pr", "list",
-		"-R", owner + "/" + repo,
-		"--json", "number,title,headRefName,baseRefName,url,isDraft,author,mergeable,mergeStateStatus,reviewDecision,labels,statusCheckRollup",
-		"--limit", "100",
+func (p graphQLPR) toPRInfo() PRInfo {
+	pr := PRInfo{
+		Number:           p.Number,
+		Title:            p.Title,
+		HeadRef:          p.HeadRefName,
+		HeadSHA:          p.HeadRefOid,
+		BaseRef:          p.BaseRefName,
+		URL:              p.URL,
+		Body:             p.Body,
+		IsDraft:          p.IsDraft,
+		Author:           p.Author,
+		Mergeable:        p.Mergeable,
+		MergeStateStatus: p.MergeStateStatus,
+		ReviewDecision:   p.ReviewDecision,
+		Labels:           p.Labels.Nodes,
+		Reviews:          make([]Review, 0, len(p.Reviews.Nodes)),
+		ReviewThreads:    make([]ReviewThread, 0, len(p.ReviewThreads.Nodes)),
 	}
+	for _, r := range p.Reviews.Nodes {
+		pr.Reviews = append(pr.Reviews, r.toReview())
+	}
+	for _, t := range p.ReviewThreads.Nodes {
+		pr.ReviewThreads = append(pr.ReviewThreads, t.toReviewThread())
+	}
+	for _, r := range p.ReviewRequests.Nodes {
+		if reviewer := r.RequestedReviewer.Login + r.RequestedReviewer.Slug; reviewer != "" {
+			pr.RequestedReviews = append(pr.RequestedReviews, reviewer)
+		}
+	}
+	for _, c := range p.Comments.Nodes {
+		pr.Comments = append(pr.Comments, c.toComment())
+	}
+	pr.OlderComments = p.Comments.PageInfo.HasPreviousPage
+	pr.AutoMergeEnabled = p.AutoMergeRequest != nil
+	pr.MergeQueueEntry = p.MergeQueueEntry
+	if n := p.TimelineItems.Nodes; len(n) > 0 && n[0].Typename == "RemovedFromMergeQueueEvent" {
+		pr.Dequeued = &DequeueEvent{Reason: n[0].Reason, At: n[0].CreatedAt}
+	}
+	if len(p.Commits.Nodes) > 0 && p.Commits.Nodes[0].Commit.StatusCheckRollup != nil {
+		pr.Checks = normalizeChecks(p.Commits.Nodes[0].Commit.StatusCheckRollup.Contexts.Nodes)
+	}
+	return pr
+}
 
-	out, err := c.gh(ctx, args...)
-	if err != nil {
-		return nil, fmt.Errorf("list PRs: %w", err)
+// complete fills in checks, reviews, review threads and thread comments that
+// didn't fit in the batched query.
+func (c *Client) complete(ctx context.Context, owner, repo string, p graphQLPR, pr *PRInfo) error {
+	if len(p.Commits.Nodes) > 0 && p.Commits.Nodes[0].Commit.StatusCheckRollup != nil {
+		if contexts := p.Commits.Nodes[0].Commit.StatusCheckRollup.Contexts; contexts.PageInfo.HasNextPage {
+			rest, err := c.checkContexts(ctx, owner, repo, pr.HeadSHA, contexts.PageInfo.EndCursor)
+			if err != nil {
+				return err
+			}
+			pr.Checks = append(pr.Checks, normalizeChecks(rest)...)
+		}
+	}
+	if p.Reviews.PageInfo.HasNextPage {
+		reviews, err := c.GetReviews(ctx, owner, repo, pr.Number)
+		if err != nil {
+			return err
+		}
+		pr.Reviews = reviews
+	}
+	if p.ReviewThreads.PageInfo.HasNextPage {
+		threads, err := c.GetReviewThreads(ctx, owner, repo, pr.Number)
+		if err != nil {
+			return err
+		}
+		pr.ReviewThreads = threads
+		return nil
+	}
+	for i, t := range p.ReviewThreads.Nodes {
+		if !t.Comments.PageInfo.HasNextPage {
+			continue
+		}
+		rest, err := c.threadComments(ctx, t.ID, t.Comments.PageInfo.EndCursor)
+		if err != nil {
+			return err
+		}
+		pr.ReviewThreads[i].Comments = append(pr.ReviewThreads[i].Comments, rest...)
 	}
+	return nil
+}
 
-	var prs []PRInfo
-	if err := json.Unmarshal(out, &prs); err != nil {
-		return nil, fmt.Errorf("parse PRs: %w", err)
+// checkContexts returns the check runs and commit statuses of a commit after
+// cursor.
+func (c *Client) checkContexts(ctx context.Context, owner, repo, sha, cursor string) ([]checkNode, error) {
+	query := `query($owner: String!, $repo: String!, $sha: GitObjectID!, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    object(oid: $sha) {
+      ... on Commit {
+        statusCheckRollup {
+          contexts(first: 100, after: $cursor) {
+            pageInfo {
+              hasNextPage
+              endCursor
+            }
+            nodes { ...checkFields }
+          }
+        }
+      }
+    }
+  }
+}
+` + checkFields
+
+	var nodes []checkNode
+	for {
+		vars := map[string]any{"owner": owner, "repo": repo, "sha": sha, "cursor": cursor}
+
+		var resp struct {
+			Repository struct {
+				Object struct {
+					StatusCheckRollup *struct {
+						Contexts struct {
+							PageInfo pageInfo    `json:"pageInfo"`
+							Nodes    []checkNode `json:"nodes"`
+						} `json:"contexts"`
+					} `json:"statusCheckRollup"`
+				} `json:"object"`
+			} `json:"repository"`
+		}
+		if err := c.graphQL(ctx, query, vars, &resp); err != nil {
+			return nil, fmt.Errorf("get checks of %s: %w", sha, err)
+		}
+		rollup := resp.Repository.Object.StatusCheckRollup
+		if rollup == nil {
+			break
+		}
+
+		nodes = append(nodes, rollup.Contexts.Nodes...)
+
+		if !rollup.Contexts.PageInfo.HasNextPage {
+			break
+		}
+		cursor = rollup.Contexts.PageInfo.EndCursor
+	}
+
+	return nodes, nil
+}
+
+// threadComments returns a review thread's comments after cursor.
+func (c *Client) threadComments(ctx context.Context, threadID, cursor string) ([]ReviewComment, error) {
+	query := `query($id: ID!, $cursor: String) {
+  node(id: $id) {
+    ... on PullRequestReviewThread {
+      comments(first: 100, after: $cursor) {
+        pageInfo {
+          hasNextPage
+          endCursor
+        }
+        nodes {
+          author { login }
+          body
+        }
+      }
+    }
+  }
+}`
+
+	var comments []ReviewComment
+	for {
+		var resp struct {
+			Node struct {
+				Comments struct {
+					PageInfo pageInfo         `json:"pageInfo"`
+					Nodes    []graphQLComment `json:"nodes"`
+				} `json:"comments"`
+			} `json:"node"`
+		}
+		vars := map[string]any{"id": threadID, "cursor": cursor}
+		if err := c.graphQL(ctx, query, vars, &resp); err != nil {
+			return nil, fmt.Errorf("get thread %s comments: %w", threadID, err)
+		}
+
+		for _, cm := range resp.Node.Comments.Nodes {
+			comments = append(comments, ReviewComment{Author: cm.Author.Login, Body: cm.Body})
+		}
+
+		if !resp.Node.Comments.PageInfo.HasNextPage {
+			return comments, nil
+		}
+		cursor = resp.Node.Comments.PageInfo.EndCursor
 	}
+}
+
+type pageInfo struct {
+	HasNextPage     bool   `json:"hasNextPage"`
+	EndCursor       string `json:"endCursor"`
+	HasPreviousPage bool   `json:"hasPreviousPage"`
+	StartCursor     string `json:"startCursor"`
+}
+
+// ListOpenPRs returns open PRs with checks, reviews and review threads,
+// fetched with one paginated query per repo. A positive limit caps how many
+// PRs (newest first) are returned; 0 returns all of them.
+func (c *Client) ListOpenPRs(ctx context.Context, owner, repo string, limit int) ([]PRInfo, error) {
+	query := `query($owner: String!, $repo: String!, $first: Int!, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    pullRequests(states: OPEN, first: $first, after: $cursor, orderBy: {field: CREATED_AT, direction: DESC}) {
+      pageInfo {
+        hasNextPage
+        endCursor
+      }
+      nodes { ...prFields }
+    }
+  }
+}
+` + prFields
+
+	var prs []PRInfo
+	cursor := ""
 
-	for i := range prs {
-		prs[i].Checks = normalizeChecks(prs[i].StatusCheckRollup)
+	for {
+		// Small pages keep the nested reviews/threads within GraphQL node limits
+		first := 25
+		if limit > 0 {
+			first = min(first, limit-len(prs))
+		}
+		vars := map[string]any{"owner": owner, "repo": repo, "first": first}
+		if cursor != "" {
+			vars["cursor"] = cursor
+		}
+
+		var resp struct {
+			Repository struct {
+				PullRequests struct {
+					PageInfo pageInfo    `json:"pageInfo"`
+					Nodes    []graphQLPR `json:"nodes"`
+				} `json:"pullRequests"`
+			} `json:"repository"`
+		}
+		if err := c.graphQL(ctx, query, vars, &resp); err != nil {
+			return nil, fmt.Errorf("list PRs: %w", err)
+		}
+
+		for _, p := range resp.Repository.PullRequests.Nodes {
+			pr := p.toPRInfo()
+			if err := c.complete(ctx, owner, repo, p, &pr); err != nil {
+				return nil, fmt.Errorf("list PRs: %w", err)
+			}
+			prs = append(prs, pr)
+		}
+
+		if !resp.Repository.PullRequests.PageInfo.HasNextPage || (limit > 0 && len(prs) >= limit) {
+			break
+		}
+		cursor = resp.Repository.PullRequests.PageInfo.EndCursor
 	}
 
 	return prs, nil
 }
 
 func (c *Client) GetPRDetail(ctx context.Context, owner, repo string, number int) (*PRInfo, error) {
-	args := []string{
-		"pr", "view", fmt.Sprintf("%d", number),
-		"-R", owner + "/" + repo,
-		"--json", "number,title,headRefName,baseRefName,url,isDraft,author,mergeable,mergeStateStatus,reviewDecision,labels,statusCheckRollup",
-	}
+	query := `query($owner: String!, $repo: String!, $pr: Int!) {
+  repository(owner: $owner, name: $repo) {
+    pullRequest(number: $pr) { ...prFields }
+  }
+}
+` + prFields
 
-	out, err := c.gh(ctx, args...)
-	if err != nil {
+	var resp struct {
+		Repository struct {
+			PullRequest *graphQLPR `json:"pullRequest"`
+		} `json:"repository"`
+	}
+	vars := map[string]any{"owner": owner, "repo": repo, "pr": number}
+	if err := c.graphQL(ctx, query, vars, &resp); err != nil {
 		return nil, fmt.Errorf("get PR #%d: %w", number, err)
 	}
-
-	var pr PRInfo
-	if err := json.Unmarshal(out, &pr); err != nil {
-		return nil, fmt.Errorf("parse PR #%d: %w", number, err)
+	if resp.Repository.PullRequest == nil {
+		return nil, &NotFoundError{APIError{Type: "NOT_FOUND", Message: fmt.Sprintf("PR #%d not found", number)}}
 	}
 
-	pr.Checks = normalizeChecks(pr.StatusCheckRollup)
+	pr := resp.Repository.PullRequest.toPRInfo()
+	if err := c.complete(ctx, owner, repo, *resp.Repository.PullRequest, &pr); err != nil {
+		return nil, fmt.Errorf("get PR #%d: %w", number, err)
+	}
 	return &pr, nil
 }
 
-type graphQLResponse struct {
-	Data struct {
-		Repository struct {
-			PullRequest struct {
-				ReviewThreads struct {
-					PageInfo struct {
-						HasNextPage bool   `json:"hasNextPage"`
-						EndCursor   string `json:"endCursor"`
-					} `json:"pageInfo"`
-					Nodes []graphQLThread `json:"nodes"`
-				} `json:"reviewThreads"`
-			} `json:"pullRequest"`
-		} `json:"repository"`
-	} `json:"data"`
+type reviewThreadsData struct {
+	Repository struct {
+		PullRequest struct {
+			ReviewThreads struct {
+				PageInfo pageInfo        `json:"pageInfo"`
+				Nodes    []graphQLThread `json:"nodes"`
+			} `json:"reviewThreads"`
+		} `json:"pullRequest"`
+	} `json:"repository"`
 }
 
 type graphQLThread struct {
@@ -143,10 +719,28 @@ type graphQLThread struct {
 	Path       string `json:"path"`
 	Line       int    `json:"line"`
 	Comments   struct {
-		Nodes []graphQLComment `json:"nodes"`
+		PageInfo pageInfo         `json:"pageInfo"`
+		Nodes    []graphQLComment `json:"nodes"`
 	} `json:"comments"`
 }
 
+func (t graphQLThread) toReviewThread() ReviewThread {
+	rt := ReviewThread{
+		ID:         t.ID,
+		IsResolved: t.IsResolved,
+		IsOutdated: t.IsOutdated,
+		Path:       t.Path,
+		Line:       t.Line,
+	}
+	for _, c := range t.Comments.Nodes {
+		rt.Comments = append(rt.Comments, ReviewComment{
+			Author: c.Author.Login,
+			Body:   c.Body,
+		})
+	}
+	return rt
+}
+
 type graphQLComment struct {
 	Author struct {
 		Login string `json:"login"`
@@ -154,6 +748,60 @@ type graphQLComment struct {
 	Body string `json:"body"`
 }
 
+// graphQLIssueComment is a PR conversation comment selected by commentFields.
+type graphQLIssueComment struct {
+	ID     string `json:"id"`
+	Author struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Body            string    `json:"body"`
+	CreatedAt       time.Time `json:"createdAt"`
+	ViewerDidAuthor bool      `json:"viewerDidAuthor"`
+	ReactionGroups  []struct {
+		Content          string `json:"content"`
+		ViewerHasReacted bool   `json:"viewerHasReacted"`
+	} `json:"reactionGroups"`
+}
+
+func (c graphQLIssueComment) toComment() Comment {
+	comment := Comment{ID: c.ID, Author: c.Author.Login, Body: c.Body, CreatedAt: c.CreatedAt, ByViewer: c.ViewerDidAuthor}
+	for _, g := range c.ReactionGroups {
+		if g.ViewerHasReacted && (g.Content == ReactionThumbsUp || g.Content == ReactionConfused) {
+			comment.Reacted = true
+		}
+	}
+	return comment
+}
+
+type graphQLReview struct {
+	ID     string `json:"id"`
+	Body   string `json:"body"`
+	Author struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	State       string     `json:"state"`
+	SubmittedAt *time.Time `json:"submittedAt"`
+	Commit      *struct {
+		Oid string `json:"oid"`
+	} `json:"commit"`
+}
+
+func (r graphQLReview) toReview() Review {
+	review := Review{
+		ID:     r.ID,
+		Author: r.Author.Login,
+		Body:   r.Body,
+		State:  r.State,
+	}
+	if r.SubmittedAt != nil {
+		review.SubmittedAt = *r.SubmittedAt
+	}
+	if r.Commit != nil {
+		review.CommitSHA = r.Commit.Oid
+	}
+	return review
+}
+
 func (c *Client) GetReviewThreads(ctx context.Context, owner, repo string, number int) ([]ReviewThread, error) {
 	query := `query($owner: String!, $repo: String!, $pr: Int!, $cursor: String) {
   repository(owner: $owner, name: $repo) {
@@ -170,6 +818,10 @@ func (c *Client) GetReviewThreads(ctx context.Context, owner, repo string, numbe
           path
           line
           comments(first: 100) {
+            pageInfo {
+              hasNextPage
+              endCursor
+            }
             nodes {
               author { login }
               body
@@ -185,87 +837,94 @@ func (c *Client) GetReviewThreads(ctx context.Context, owner, repo string, numbe
 	cursor := ""
 
 	for {
-		args := []string{
-			"api", "graphql",
-			"-f", "owner=" + owner,
-			"-f", "repo=" + repo,
-			"-F", fmt.Sprintf("pr=%d", number),
-			"-f", "query=" + query,
-		}
+		vars := map[string]any{"owner": owner, "repo": repo, "pr": number}
 		if cursor != "" {
-			args = append(args, "-f", "cursor="+cursor)
+			vars["cursor"] = cursor
 		}
 
-		out, err := c.gh(ctx, args...)
-		if err != nil {
+		var resp reviewThreadsData
+		if err := c.graphQL(ctx, query, vars, &resp); err != nil {
 			return nil, fmt.Errorf("get review threads PR #%d: %w", number, err)
 		}
 
-		var resp graphQLResponse
-		if err := json.Unmarshal(out, &resp); err != nil {
-			return nil, fmt.Errorf("parse review threads: %w", err)
-		}
-
-		for _, t := range resp.Data.Repository.PullRequest.ReviewThreads.Nodes {
-			rt := ReviewThread{
-				ID:         t.ID,
-				IsResolved: t.IsResolved,
-				IsOutdated: t.IsOutdated,
-				Path:       t.Path,
-				Line:       t.Line,
-			}
-			for _, c := range t.Comments.Nodes {
-				rt.Comments = append(rt.Comments, ReviewComment{
-					Author: c.Author.Login,
-					Body:   c.Body,
-				})
+		for _, t := range resp.Repository.PullRequest.ReviewThreads.Nodes {
+			rt := t.toReviewThread()
+			if t.Comments.PageInfo.HasNextPage {
+				rest, err := c.threadComments(ctx, t.ID, t.Comments.PageInfo.EndCursor)
+				if err != nil {
+					return nil, err
+				}
+				rt.Comments = append(rt.Comments, rest...)
 			}
 			threads = append(threads, rt)
 		}
 
-		if !resp.Data.Repository.PullRequest.ReviewThreads.PageInfo.HasNextPage {
+		if !resp.Repository.PullRequest.ReviewThreads.PageInfo.HasNextPage {
 			break
 		}
-		cursor = resp.Data.Repository.PullRequest.ReviewThreads.PageInfo.EndCursor
+		cursor = resp.Repository.PullRequest.ReviewThreads.PageInfo.EndCursor
 	}
 
 	return threads, nil
 }
 
 func (c *Client) GetReviews(ctx context.Context, owner, repo string, number int) ([]Review, error) {
-	args := []string{
-		"pr", "view", fmt.Sprintf("%d", number),
-		"-R", owner + "/" + repo,
-		"--json", "reviews",
-	}
-
-	out, err := c.gh(ctx, args...)
-	if err != nil {
-		return nil, fmt.Errorf("get reviews PR #%d: %w", number, err)
-	}
-
-	var resp struct {
-		Reviews []struct {
-			Author struct {
-				Login string `json:"login"`
-			} `json:"author"`
-			State string `json:"state"`
-		} `json:"reviews"`
-	}
-	if err := json.Unmarshal(out, &resp); err != nil {
-		return nil, fmt.Errorf("parse reviews: %w", err)
-	}
+	query := `query($owner: String!, $repo: String!, $pr: Int!, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    pullRequest(number: $pr) {
+      reviews(first: 100, after: $cursor) {
+        pageInfo {
+          hasNextPage
+          endCursor
+        }
+        nodes {
+          id
+          author { login }
+          body
+          state
+          submittedAt
+          commit { oid }
+        }
+      }
+    }
+  }
+}`
 
-	reviews := make([]Review, 0, len(resp.Reviews))
-	for _, r := range resp.Reviews {
-		reviews = append(reviews, Review{
-			Author: r.Author.Login,
-			State:  r.State,
-		})
-	}
+	reviews := []Review{}
+	cursor := ""
 
-	return reviews, nil
-}
+	for {
+		vars := map[string]any{"owner": owner, "repo": repo, "pr": number}
+		if cursor != "" {
+			vars["cursor"] = cursor
+		}
+
+		var resp struct {
+			Repository struct {
+				PullRequest struct {
+					Reviews struct {
+						PageInfo pageInfo        `json:"pageInfo"`
+						Nodes    []graphQLReview `json:"nodes"`
+					} `json:"reviews"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}
+		if err := c.graphQL(ctx, query, vars, &resp); err != nil {
+			return nil, fmt.Errorf("get reviews PR #%d: %w", number, err)
+		}
+
+		for _, r := range resp.Repository.PullRequest.Reviews.Nodes {
+			reviews = append(reviews, r.toReview())
+		}
+
+		if !resp.Repository.PullRequest.Reviews.PageInfo.HasNextPage {
+			break
+		}
+		cursor = resp.Repository.PullRequest.Reviews.PageInfo.EndCursor
+	}
+
+	return reviews, nil
+}
 
 func (c *Client) ResolveReviewThread(ctx context.Context, threadID string) error {
 	mutation := `mutation($threadID: ID!) {
@@ -277,210 +936,904 @@ func (c *Client) ResolveReviewThread(ctx context.Context, threadID string) error
   }
 }`
 
-	args := []string{
-		"api", "graphql",
-		"-f", "threadID=" + threadID,
-		"-f", "query=" + mutation,
+	vars := map[string]any{"threadID": threadID}
+	if err := c.graphQL(ctx, mutation, vars, nil); err != nil {
+		return fmt.Errorf("resolve review thread %s: %w", threadID, err)
 	}
 
-	_, err := c.gh(ctx, args...)
-	if err != nil {
-		return fmt.Errorf("resolve review thread %s: %w", threadID, err)
+	return nil
+}
+
+// AddThreadReply replies to a review thread.
+func (c *Client) AddThreadReply(ctx context.Context, threadID, body string) error {
+	mutation := `mutation($threadID: ID!, $body: String!) {
+  addPullRequestReviewThreadReply(input: {pullRequestReviewThreadId: $threadID, body: $body}) {
+    comment {
+      id
+    }
+  }
+}`
+
+	vars := map[string]any{"threadID": threadID, "body": body}
+	if err := c.graphQL(ctx, mutation, vars, nil); err != nil {
+		return fmt.Errorf("reply to review thread %s: %w", threadID, err)
+	}
+
+	return nil
+}
+
+// AddReaction reacts to a comment or other reactable node with content, one
+// of the Reaction* constants.
+func (c *Client) AddReaction(ctx context.Context, subjectID, content string) error {
+	mutation := `mutation($subjectID: ID!, $content: ReactionContent!) {
+  addReaction(input: {subjectId: $subjectID, content: $content}) {
+    reaction {
+      content
+    }
+  }
+}`
+
+	vars := map[string]any{"subjectID": subjectID, "content": content}
+	if err := c.graphQL(ctx, mutation, vars, nil); err != nil {
+		return fmt.Errorf("react to %s: %w", subjectID, err)
+	}
+
+	return nil
+}
+
+// GetPermission returns a user's permission on a repo: admin, write, read or
+// none. Maintainers count as write, triagers as read.
+func (c *Client) GetPermission(ctx context.Context, owner, repo, login string) (string, error) {
+	var resp struct {
+		Permission string `json:"permission"`
 	}
+	path := fmt.Sprintf("repos/%s/%s/collaborators/%s/permission", owner, repo, login)
+	if err := c.rest(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return "", fmt.Errorf("get permission of %s on %s/%s: %w", login, owner, repo, err)
+	}
+
+	return resp.Permission, nil
+}
+
+// GetLabel returns a repo label. A missing label is a *NotFoundError.
+func (c *Client) GetLabel(ctx context.Context, owner, repo, name string) (*Label, error) {
+	var label Label
+	path := fmt.Sprintf("repos/%s/%s/labels/%s", owner, repo, url.PathEscape(name))
+	if err := c.rest(ctx, http.MethodGet, path, nil, &label); err != nil {
+		return nil, fmt.Errorf("get label %q: %w", name, err)
+	}
+	return &label, nil
+}
 
+// CreateLabel creates a repo label.
+func (c *Client) CreateLabel(ctx context.Context, owner, repo string, label Label) error {
+	path := fmt.Sprintf("repos/%s/%s/labels", owner, repo)
+	if err := c.rest(ctx, http.MethodPost, path, label, nil); err != nil {
+		return fmt.Errorf("create label %q: %w", label.Name, err)
+	}
+	return nil
+}
+
+// UpdateLabel sets the colour and description of a repo label.
+func (c *Client) UpdateLabel(ctx context.Context, owner, repo string, label Label) error {
+	body := map[string]string{"color": label.Color, "description": label.Description}
+	path := fmt.Sprintf("repos/%s/%s/labels/%s", owner, repo, url.PathEscape(label.Name))
+	if err := c.rest(ctx, http.MethodPatch, path, body, nil); err != nil {
+		return fmt.Errorf("update label %q: %w", label.Name, err)
+	}
 	return nil
 }
 
+// AddLabels adds existing repo labels to a PR.
+func (c *Client) AddLabels(ctx context.Context, owner, repo string, number int, names []string) error {
+	body := map[string][]string{"labels": names}
+	path := fmt.Sprintf("repos/%s/%s/issues/%d/labels", owner, repo, number)
+	if err := c.rest(ctx, http.MethodPost, path, body, nil); err != nil {
+		return fmt.Errorf("add labels to PR #%d: %w", number, err)
+	}
+	return nil
+}
+
+// RemoveLabel removes a label from a PR. Removing a label the PR doesn't
+// have succeeds.
+func (c *Client) RemoveLabel(ctx context.Context, owner, repo string, number int, name string) error {
+	path := fmt.Sprintf("repos/%s/%s/issues/%d/labels/%s", owner, repo, number, url.PathEscape(name))
+	err := c.rest(ctx, http.MethodDelete, path, nil, nil)
+	var notFound *NotFoundError
+	if err != nil && !errors.As(err, &notFound) {
+		return fmt.Errorf("remove label %q from PR #%d: %w", name, number, err)
+	}
+	return nil
+}
+
+// ListTeamMembers lists the logins of an org team's members, including
+// members of child teams.
+func (c *Client) ListTeamMembers(ctx context.Context, org, team string) ([]string, error) {
+	const perPage = 100
+
+	var logins []string
+	for page := 1; ; page++ {
+		var resp []struct {
+			Login string `json:"login"`
+		}
+		path := fmt.Sprintf("orgs/%s/teams/%s/members?per_page=%d&page=%d", org, team, perPage, page)
+		if err := c.rest(ctx, http.MethodGet, path, nil, &resp); err != nil {
+			return nil, fmt.Errorf("list members of team %s/%s: %w", org, team, err)
+		}
+
+		for _, r := range resp {
+			logins = append(logins, r.Login)
+		}
+
+		if len(resp) < perPage {
+			break
+		}
+	}
+
+	return logins, nil
+}
+
 func (c *Client) UpdateBranch(ctx context.Context, owner, repo string, number int) error {
-	mutation := `mutation($prID: ID!) {
-  updatePullRequestBranch(input: {pullRequestId: $prID}) {
+	return c.updateBranch(ctx, owner, repo, number, "MERGE")
+}
+
+// RebaseBranch rebases the PR's head branch onto its base.
+func (c *Client) RebaseBranch(ctx context.Context, owner, repo string, number int) error {
+	return c.updateBranch(ctx, owner, repo, number, "REBASE")
+}
+
+// updateBranch brings the head branch up to date with the base, with a merge
+// commit or by rebasing it.
+func (c *Client) updateBranch(ctx context.Context, owner, repo string, number int, method string) error {
+	mutation := `mutation($prID: ID!, $method: PullRequestBranchUpdateMethod) {
+  updatePullRequestBranch(input: {pullRequestId: $prID, updateMethod: $method}) {
     pullRequest {
       id
     }
   }
 }`
 
-	// Get PR node ID first
-	prIDQuery := `query($owner: String!, $repo: String!, $num: Int!) {
-  repository(owner: $owner, name: $repo) {
-    pullRequest(number: $num) {
+	prID, err := c.pullRequestID(ctx, owner, repo, number)
+	if err != nil {
+		return err
+	}
+
+	vars := map[string]any{"prID": prID, "method": method}
+	if err := c.graphQL(ctx, mutation, vars, nil); err != nil {
+		return fmt.Errorf("update branch: %w", err)
+	}
+
+	return nil
+}
+
+// EnableAutoMerge turns on GitHub's auto-merge, which merges the PR once its
+// branch protection requirements pass. Returns CleanStatusError if the PR
+// can be merged right away.
+func (c *Client) EnableAutoMerge(ctx context.Context, owner, repo string, number int, opts MergeOptions) error {
+	mutation := `mutation($prID: ID!, $method: PullRequestMergeMethod!, $headline: String, $body: String) {
+  enablePullRequestAutoMerge(input: {pullRequestId: $prID, mergeMethod: $method, commitHeadline: $headline, commitBody: $body}) {
+    pullRequest {
       id
     }
   }
 }`
 
-	args := []string{
-		"api", "graphql",
-		"-f", "owner=" + owner,
-		"-f", "repo=" + repo,
-		"-F", fmt.Sprintf("num=%d", number),
-		"-f", "query=" + prIDQuery,
-	}
-
-	out, err := c.gh(ctx, args...)
+	prID, err := c.pullRequestID(ctx, owner, repo, number)
 	if err != nil {
-		return fmt.Errorf("get PR ID: %w", err)
+		return err
 	}
 
-	var resp struct {
-		Data struct {
-			Repository struct {
-				PullRequest struct {
-					ID string `json:"id"`
-				} `json:"pullRequest"`
-			} `json:"repository"`
-		} `json:"data"`
+	vars := map[string]any{"prID": prID, "method": strings.ToUpper(mergeMethod(opts.Method))}
+	if opts.CommitTitle != "" && opts.Method != "rebase" {
+		vars["headline"] = opts.CommitTitle
+	}
+	if opts.CommitMessage != "" && opts.Method != "rebase" {
+		vars["body"] = opts.CommitMessage
 	}
-	if err := json.Unmarshal(out, &resp); err != nil {
-		return fmt.Errorf("parse PR ID: %w", err)
+	if err := c.graphQL(ctx, mutation, vars, nil); err != nil {
+		return fmt.Errorf("enable auto-merge on PR #%d: %w", number, err)
 	}
 
-	prID := resp.Data.Repository.PullRequest.ID
-	if prID == "" {
-		return fmt.Errorf("PR ID not found")
+	return nil
+}
+
+// EnqueuePR adds the PR to its base branch's merge queue.
+func (c *Client) EnqueuePR(ctx context.Context, owner, repo string, number int) error {
+	mutation := `mutation($prID: ID!) {
+  enqueuePullRequest(input: {pullRequestId: $prID}) {
+    mergeQueueEntry {
+      position
+    }
+  }
+}`
+
+	prID, err := c.pullRequestID(ctx, owner, repo, number)
+	if err != nil {
+		return err
 	}
 
-	// Update branch
-	args = []string{
-		"api", "graphql",
-		"-f", "prID=" + prID,
-		"-f", "query=" + mutation,
+	vars := map[string]any{"prID": prID}
+	if err := c.graphQL(ctx, mutation, vars, nil); err != nil {
+		return fmt.Errorf("enqueue PR #%d: %w", number, err)
 	}
 
-	_, err = c.gh(ctx, args...)
+	return nil
+}
+
+// RequestBotReviews requests (or re-requests) reviews from bot accounts
+// such as copilot-pull-request-reviewer, keeping existing requests.
+func (c *Client) RequestBotReviews(ctx context.Context, owner, repo string, number int, bots []string) error {
+	mutation := `mutation($prID: ID!, $bots: [String!]) {
+  requestReviewsByLogin(input: {pullRequestId: $prID, botLogins: $bots, union: true}) {
+    clientMutationId
+  }
+}`
+
+	prID, err := c.pullRequestID(ctx, owner, repo, number)
 	if err != nil {
-		return fmt.Errorf("update branch: %w", err)
+		return err
+	}
+
+	vars := map[string]any{"prID": prID, "bots": bots}
+	if err := c.graphQL(ctx, mutation, vars, nil); err != nil {
+		return fmt.Errorf("request reviews on PR #%d from %v: %w", number, bots, err)
 	}
 
 	return nil
 }
 
-func (c *Client) PostComment(ctx context.Context, owner, repo string, number int, body string) error {
-	args := []string{
-		"pr", "comment", fmt.Sprintf("%d", number),
-		"-R", owner + "/" + repo,
-		"-b", body,
+// pullRequestID looks up the GraphQL node ID mutations need.
+func (c *Client) pullRequestID(ctx context.Context, owner, repo string, number int) (string, error) {
+	query := `query($owner: String!, $repo: String!, $num: Int!) {
+  repository(owner: $owner, name: $repo) {
+    pullRequest(number: $num) {
+      id
+    }
+  }
+}`
+
+	var resp struct {
+		Repository struct {
+			PullRequest struct {
+				ID string `json:"id"`
+			} `json:"pullRequest"`
+		} `json:"repository"`
+	}
+	vars := map[string]any{"owner": owner, "repo": repo, "num": number}
+	if err := c.graphQL(ctx, query, vars, &resp); err != nil {
+		return "", fmt.Errorf("get PR ID: %w", err)
 	}
 
-	_, err := c.gh(ctx, args...)
-	if err != nil {
+	prID := resp.Repository.PullRequest.ID
+	if prID == "" {
+		return "", fmt.Errorf("PR ID not found")
+	}
+	return prID, nil
+}
+
+func (c *Client) PostComment(ctx context.Context, owner, repo string, number int, body string) error {
+	path := fmt.Sprintf("repos/%s/%s/issues/%d/comments", owner, repo, number)
+	if err := c.rest(ctx, http.MethodPost, path, map[string]string{"body": body}, nil); err != nil {
 		return fmt.Errorf("post comment on PR #%d: %w", number, err)
 	}
 
 	return nil
 }
 
-func (c *Client) GetComments(ctx context.Context, owner, repo string, number int) ([]string, error) {
-	query := `query($owner: String!, $repo: String!, $pr: Int!) {
+// GetComments returns all comments in a PR's conversation, oldest first.
+func (c *Client) GetComments(ctx context.Context, owner, repo string, number int) ([]Comment, error) {
+	return c.GetCommentsSince(ctx, owner, repo, number, time.Time{})
+}
+
+// GetCommentsSince returns the comments in a PR's conversation created at or
+// after since, oldest first. It pages backwards from the newest comment, so
+// only the pages it needs are fetched.
+func (c *Client) GetCommentsSince(ctx context.Context, owner, repo string, number int, since time.Time) ([]Comment, error) {
+	query := `query($owner: String!, $repo: String!, $pr: Int!, $cursor: String) {
   repository(owner: $owner, name: $repo) {
     pullRequest(number: $pr) {
-      comments(last: 100) {
-        nodes {
-          body
+      comments(last: 100, before: $cursor) {
+        pageInfo {
+          hasPreviousPage
+          startCursor
         }
+        nodes { ...commentFields }
       }
     }
   }
-}`
+}
+` + commentFields
 
-	args := []string{
-		"api", "graphql",
-		"-f", "owner=" + owner,
-		"-f", "repo=" + repo,
-		"-F", fmt.Sprintf("pr=%d", number),
-		"-f", "query=" + query,
-	}
+	var comments []Comment
+	cursor := ""
 
-	out, err := c.gh(ctx, args...)
-	if err != nil {
-		return nil, fmt.Errorf("get comments PR #%d: %w", number, err)
-	}
+	for {
+		vars := map[string]any{"owner": owner, "repo": repo, "pr": number}
+		if cursor != "" {
+			vars["cursor"] = cursor
+		}
 
-	var resp struct {
-		Data struct {
+		var resp struct {
 			Repository struct {
 				PullRequest struct {
 					Comments struct {
-						Nodes []struct {
-							Body string `json:"body"`
-						} `json:"nodes"`
+						PageInfo pageInfo              `json:"pageInfo"`
+						Nodes    []graphQLIssueComment `json:"nodes"`
 					} `json:"comments"`
 				} `json:"pullRequest"`
 			} `json:"repository"`
-		} `json:"data"`
-	}
-	if err := json.Unmarshal(out, &resp); err != nil {
-		return nil, fmt.Errorf("parse comments: %w", err)
-	}
+		}
+		if err := c.graphQL(ctx, query, vars, &resp); err != nil {
+			return nil, fmt.Errorf("get comments PR #%d: %w", number, err)
+		}
 
-	var comments []string
-	for _, node := range resp.Data.Repository.PullRequest.Comments.Nodes {
-		comments = append(comments, node.Body)
+		nodes := resp.Repository.PullRequest.Comments.Nodes
+		done := false
+		for _, node := range slices.Backward(nodes) {
+			if node.CreatedAt.Before(since) {
+				done = true
+				break
+			}
+			comments = append(comments, node.toComment())
+		}
+
+		if done || !resp.Repository.PullRequest.Comments.PageInfo.HasPreviousPage {
+			break
+		}
+		cursor = resp.Repository.PullRequest.Comments.PageInfo.StartCursor
 	}
 
+	slices.Reverse(comments)
 	return comments, nil
 }
 
-func (c *Client) MergePR(ctx context.Context, owner, repo string, number int, method string) error {
-	args := []string{
-		"pr", "merge", fmt.Sprintf("%d", number),
-		"-R", owner + "/" + repo,
-		"--delete-branch",
+// UpdateComment replaces the body of a PR conversation comment.
+func (c *Client) UpdateComment(ctx context.Context, commentID, body string) error {
+	mutation := `mutation($id: ID!, $body: String!) {
+  updateIssueComment(input: {id: $id, body: $body}) {
+    issueComment {
+      id
+    }
+  }
+}`
+
+	vars := map[string]any{"id": commentID, "body": body}
+	if err := c.graphQL(ctx, mutation, vars, nil); err != nil {
+		return fmt.Errorf("update comment %s: %w", commentID, err)
 	}
 
+	return nil
+}
+
+// mergeMethod defaults unknown methods to squash.
+func mergeMethod(method string) string {
 	switch method {
-	case "squash":
-		args = append(args, "--squash")
-	case "merge":
-		args = append(args, "--merge")
+	case "squash", "merge", "rebase":
+		return method
 	default:
-		args = append(args, "--squash")
+		return "squash"
 	}
+}
 
-	_, err := c.gh(ctx, args...)
-	if err != nil {
+func (c *Client) MergePR(ctx context.Context, owner, repo string, number int, opts MergeOptions) error {
+	method := mergeMethod(opts.Method)
+
+	// Look up the head branch first so it can be deleted after the merge
+	var pull struct {
+		Head struct {
+			Ref  string `json:"ref"`
+			Repo *struct {
+				FullName string `json:"full_name"`
+			} `json:"repo"`
+		} `json:"head"`
+	}
+	pullPath := fmt.Sprintf("repos/%s/%s/pulls/%d", owner, repo, number)
+	if err := c.rest(ctx, http.MethodGet, pullPath, nil, &pull); err != nil {
 		return fmt.Errorf("merge PR #%d: %w", number, err)
 	}
 
+	body := map[string]string{"merge_method": method}
+	if method != "rebase" {
+		if opts.CommitTitle != "" {
+			body["commit_title"] = opts.CommitTitle
+		}
+		if opts.CommitMessage != "" {
+			body["commit_message"] = opts.CommitMessage
+		}
+	}
+	if err := c.rest(ctx, http.MethodPut, pullPath+"/merge", body, nil); err != nil {
+		var refused *APIError
+		if errors.As(err, &refused) && refused.StatusCode == http.StatusMethodNotAllowed {
+			err = c.mergeRefused(ctx, pullPath, *refused)
+		}
+		return fmt.Errorf("merge PR #%d: %w", number, err)
+	}
+
+	// Branches from forks can't be deleted with our credentials
+	if pull.Head.Repo == nil || !strings.EqualFold(pull.Head.Repo.FullName, owner+"/"+repo) {
+		return nil
+	}
+	refPath := fmt.Sprintf("repos/%s/%s/git/refs/heads/%s", owner, repo, pull.Head.Ref)
+	if err := c.rest(ctx, http.MethodDelete, refPath, nil, nil); err != nil {
+		// Repos with automatic branch deletion race us here
+		c.logger.Warn("failed to delete head branch", "pr", number, "branch", pull.Head.Ref, "err", err)
+	}
+
+	return nil
+}
+
+// mergeRefused explains a 405 from the merge endpoint. GitHub answers "Pull
+// Request is not mergeable" for conflicts, but also for failing checks,
+// missing reviews and other branch protection rules, so only a dirty
+// mergeable_state is a *MergeConflictError. Otherwise the state is added to
+// the message.
+func (c *Client) mergeRefused(ctx context.Context, pullPath string, e APIError) error {
+	var pull struct {
+		MergeableState string `json:"mergeable_state"`
+	}
+	if err := c.rest(ctx, http.MethodGet, pullPath, nil, &pull); err != nil {
+		return &e
+	}
+	if pull.MergeableState == "dirty" {
+		return &MergeConflictError{e}
+	}
+	if pull.MergeableState != "" {
+		e.Message += " (mergeable_state: " + pull.MergeableState + ")"
+	}
+	return &e
+}
+
+// GetRequiredChecks returns the status checks required to merge into branch,
+// from both classic branch protection and rulesets. Sources the credentials
+// can't read are skipped, leaving the result unknown.
+func (c *Client) GetRequiredChecks(ctx context.Context, owner, repo, branch string) (RequiredChecks, error) {
+	required := RequiredChecks{Known: true}
+	add := func(name string) {
+		if name != "" && !slices.Contains(required.Names, name) {
+			required.Names = append(required.Names, name)
+		}
+	}
+
+	var rules []struct {
+		Type       string `json:"type"`
+		Parameters struct {
+			RequiredStatusChecks []struct {
+				Context string `json:"context"`
+			} `json:"required_status_checks"`
+		} `json:"parameters"`
+	}
+	rulesPath := fmt.Sprintf("repos/%s/%s/rules/branches/%s?per_page=100", owner, repo, url.PathEscape(branch))
+	if err := c.rest(ctx, http.MethodGet, rulesPath, nil, &rules); err != nil {
+		if !unreadable(err) {
+			return RequiredChecks{}, fmt.Errorf("get rules for %s: %w", branch, err)
+		}
+		required.Known = false
+	}
+	for _, rule := range rules {
+		if rule.Type != "required_status_checks" {
+			continue
+		}
+		for _, check := range rule.Parameters.RequiredStatusChecks {
+			add(check.Context)
+		}
+	}
+
+	// Unlike the protection endpoint, this one doesn't need admin access
+	var b struct {
+		Protection struct {
+			RequiredStatusChecks struct {
+				Contexts []string `json:"contexts"`
+				Checks   []struct {
+					Context string `json:"context"`
+				} `json:"checks"`
+			} `json:"required_status_checks"`
+		} `json:"protection"`
+	}
+	branchPath := fmt.Sprintf("repos/%s/%s/branches/%s", owner, repo, url.PathEscape(branch))
+	if err := c.rest(ctx, http.MethodGet, branchPath, nil, &b); err != nil {
+		if !unreadable(err) {
+			return RequiredChecks{}, fmt.Errorf("get protection for %s: %w", branch, err)
+		}
+		required.Known = false
+	}
+	for _, name := range b.Protection.RequiredStatusChecks.Contexts {
+		add(name)
+	}
+	for _, check := range b.Protection.RequiredStatusChecks.Checks {
+		add(check.Context)
+	}
+
+	return required, nil
+}
+
+// unreadable matches errors for resources that are missing or hidden from
+// the current credentials.
+func unreadable(err error) bool {
+	var notFound *NotFoundError
+	var authErr *AuthError
+	return errors.As(err, &notFound) || errors.As(err, &authErr)
+}
+
+// RerunCheck re-runs a completed check run: Actions jobs are re-run
+// directly, other apps' check runs are re-requested from the app.
+func (c *Client) RerunCheck(ctx context.Context, owner, repo string, check Check) error {
+	if check.ID == 0 {
+		return fmt.Errorf("rerun %q: commit statuses can't be re-run", check.Name)
+	}
+	path := fmt.Sprintf("repos/%s/%s/check-runs/%d/rerequest", owner, repo, check.ID)
+	if check.IsActionsJob() {
+		path = fmt.Sprintf("repos/%s/%s/actions/jobs/%d/rerun", owner, repo, check.ID)
+	}
+	if err := c.rest(ctx, http.MethodPost, path, nil, nil); err != nil {
+		return fmt.Errorf("rerun %q: %w", check.Name, err)
+	}
+	return nil
+}
+
+// CreateCheckRun creates a check run and returns its ID. Only GitHub Apps can
+// create check runs.
+func (c *Client) CreateCheckRun(ctx context.Context, owner, repo string, run CheckRun) (int64, error) {
+	var resp struct {
+		ID int64 `json:"id"`
+	}
+	path := fmt.Sprintf("repos/%s/%s/check-runs", owner, repo)
+	if err := c.rest(ctx, http.MethodPost, path, checkRunBody(run, true), &resp); err != nil {
+		return 0, fmt.Errorf("create check run %q: %w", run.Name, err)
+	}
+	return resp.ID, nil
+}
+
+// UpdateCheckRun replaces the status and output of a check run.
+func (c *Client) UpdateCheckRun(ctx context.Context, owner, repo string, id int64, run CheckRun) error {
+	path := fmt.Sprintf("repos/%s/%s/check-runs/%d", owner, repo, id)
+	if err := c.rest(ctx, http.MethodPatch, path, checkRunBody(run, false), nil); err != nil {
+		return fmt.Errorf("update check run %q: %w", run.Name, err)
+	}
+	return nil
+}
+
+func checkRunBody(run CheckRun, create bool) map[string]any {
+	body := map[string]any{
+		"name":   run.Name,
+		"status": run.Status,
+		"output": map[string]string{"title": run.Title, "summary": run.Summary},
+	}
+	if create {
+		body["head_sha"] = run.HeadSHA
+	}
+	if run.Conclusion != "" {
+		body["conclusion"] = run.Conclusion
+	}
+	if run.DetailsURL != "" {
+		body["details_url"] = run.DetailsURL
+	}
+	return body
+}
+
+// SetCommitStatus sets a commit status on sha.
+func (c *Client) SetCommitStatus(ctx context.Context, owner, repo, sha string, status CommitStatus) error {
+	const maxDescription = 140
+	body := map[string]string{
+		"context":     status.Context,
+		"state":       status.State,
+		"description": status.Description,
+	}
+	if r := []rune(status.Description); len(r) > maxDescription {
+		body["description"] = string(r[:maxDescription-1]) + "…"
+	}
+	if status.TargetURL != "" {
+		body["target_url"] = status.TargetURL
+	}
+	path := fmt.Sprintf("repos/%s/%s/statuses/%s", owner, repo, sha)
+	if err := c.rest(ctx, http.MethodPost, path, body, nil); err != nil {
+		return fmt.Errorf("set commit status %q: %w", status.Context, err)
+	}
+	return nil
+}
+
+// GetJobLogs downloads the plain-text log of a GitHub Actions job.
+func (c *Client) GetJobLogs(ctx context.Context, owner, repo string, jobID int64) (string, error) {
+	path := fmt.Sprintf("repos/%s/%s/actions/jobs/%d/logs", owner, repo, jobID)
+	resp, err := c.do(ctx, http.MethodGet, path, nil, true)
+	if err != nil {
+		return "", fmt.Errorf("get logs of job %d: %w", jobID, err)
+	}
+	return string(resp.Body), nil
+}
+
+// GetCheckAnnotations lists a check run's annotations. Actions job IDs are
+// also check run IDs.
+func (c *Client) GetCheckAnnotations(ctx context.Context, owner, repo string, checkID int64) ([]Annotation, error) {
+	const perPage = 100
+
+	var annotations []Annotation
+	for page := 1; ; page++ {
+		var resp []struct {
+			Path            string `json:"path"`
+			StartLine       int    `json:"start_line"`
+			AnnotationLevel string `json:"annotation_level"`
+			Title           string `json:"title"`
+			Message         string `json:"message"`
+		}
+		path := fmt.Sprintf("repos/%s/%s/check-runs/%d/annotations?per_page=%d&page=%d", owner, repo, checkID, perPage, page)
+		if err := c.rest(ctx, http.MethodGet, path, nil, &resp); err != nil {
+			return nil, fmt.Errorf("get annotations of check run %d: %w", checkID, err)
+		}
+
+		for _, r := range resp {
+			annotations = append(annotations, Annotation{
+				Path:    r.Path,
+				Line:    r.StartLine,
+				Level:   r.AnnotationLevel,
+				Title:   r.Title,
+				Message: r.Message,
+			})
+		}
+
+		if len(resp) < perPage {
+			break
+		}
+	}
+
+	return annotations, nil
+}
+
+// GetPRCommits lists the PR's commits, oldest first. GitHub returns at most
+// 250.
+func (c *Client) GetPRCommits(ctx context.Context, owner, repo string, number int) ([]Commit, error) {
+	const perPage = 100
+
+	var commits []Commit
+	for page := 1; ; page++ {
+		var resp []struct {
+			SHA    string `json:"sha"`
+			Commit struct {
+				Message string `json:"message"`
+				Author  struct {
+					Name  string `json:"name"`
+					Email string `json:"email"`
+				} `json:"author"`
+			} `json:"commit"`
+			Author *struct {
+				Login string `json:"login"`
+			} `json:"author"`
+		}
+		path := fmt.Sprintf("repos/%s/%s/pulls/%d/commits?per_page=%d&page=%d", owner, repo, number, perPage, page)
+		if err := c.rest(ctx, http.MethodGet, path, nil, &resp); err != nil {
+			return nil, fmt.Errorf("get commits of PR #%d: %w", number, err)
+		}
+
+		for _, r := range resp {
+			commit := Commit{
+				SHA:         r.SHA,
+				Message:     r.Commit.Message,
+				AuthorName:  r.Commit.Author.Name,
+				AuthorEmail: r.Commit.Author.Email,
+			}
+			if r.Author != nil {
+				commit.AuthorLogin = r.Author.Login
+			}
+			commits = append(commits, commit)
+		}
+
+		if len(resp) < perPage {
+			break
+		}
+	}
+
+	return commits, nil
+}
+
+type graphQLError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// graphQL runs a query or mutation and decodes its data into out (if non-nil).
+func (c *Client) graphQL(ctx context.Context, query string, vars map[string]any, out any) error {
+	payload, err := json.Marshal(map[string]any{"query": query, "variables": vars})
+	if err != nil {
+		return fmt.Errorf("encode graphql request: %w", err)
+	}
+
+	// Mutations are only retried when rate limited: a 5xx doesn't tell
+	// whether they were applied
+	idempotent := !strings.HasPrefix(strings.TrimSpace(query), "mutation")
+	resp, err := c.do(ctx, http.MethodPost, "graphql", payload, idempotent)
+	if err != nil {
+		return err
+	}
+
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []graphQLError  `json:"errors"`
+	}
+	if err := json.Unmarshal(resp.Body, &envelope); err != nil {
+		return fmt.Errorf("parse graphql response: %w", err)
+	}
+	if len(envelope.Errors) > 0 {
+		msgs := make([]string, 0, len(envelope.Errors))
+		for _, e := range envelope.Errors {
+			msgs = append(msgs, e.Message)
+		}
+		// The first error's type classifies the whole response
+		return classify(APIError{
+			Type:    envelope.Errors[0].Type,
+			Message: "graphql: " + strings.Join(msgs, "; "),
+		}, nil)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(envelope.Data, out); err != nil {
+			return fmt.Errorf("parse graphql data: %w", err)
+		}
+	}
 	return nil
 }
 
-func (c *Client) gh(ctx context.Context, args ...string) ([]byte, error) {
-	c.logger.Debug("gh", "args", strings.Join(args, " "))
-	cmd := exec.CommandContext(ctx, "gh", args...)
-	out, err := cmd.Output()
+// rest sends a REST request with an optional JSON body and decodes the JSON
+// response into out (if non-nil).
+func (c *Client) rest(ctx context.Context, method, path string, in, out any) error {
+	var payload []byte
+	if in != nil {
+		var err error
+		payload, err = json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+	}
+
+	idempotent := method != http.MethodPost && method != http.MethodPatch
+	resp, err := c.do(ctx, method, path, payload, idempotent)
 	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return nil, fmt.Errorf("%w: %s", err, string(exitErr.Stderr))
+		return err
+	}
+
+	if out != nil && len(resp.Body) > 0 {
+		if err := json.Unmarshal(resp.Body, out); err != nil {
+			return fmt.Errorf("parse response: %w", err)
 		}
+	}
+	return nil
+}
+
+// do sends a request, retrying rate-limited ones, and idempotent ones on
+// transient or network failures, with jittered exponential backoff.
+func (c *Client) do(ctx context.Context, method, path string, payload []byte, idempotent bool) (*Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(ctx, method, path, payload)
+		if err == nil {
+			return resp, nil
+		}
+		if attempt >= maxAttempts || ctx.Err() != nil || !retryable(err, idempotent) {
+			return nil, err
+		}
+
+		delay := c.backoff(attempt)
+		c.logger.Debug("retrying GitHub request", "method", method, "path", path, "attempt", attempt, "delay", delay, "err", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// send makes a single request and turns non-2xx responses into classified
+// errors.
+func (c *Client) send(ctx context.Context, method, path string, payload []byte) (*Response, error) {
+	resource := "core"
+	if path == "graphql" {
+		resource = "graphql"
+	}
+	if err := c.rate.wait(ctx, resource); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.transport.Do(ctx, method, path, payload)
+	if err != nil {
 		return nil, err
 	}
-	return out, nil
+	c.rate.observe(resp)
+
+	// GraphQL reports rate limits in errors[] of a 200 response; classify
+	// them here so they're retried like REST ones
+	if path == "graphql" && resp.StatusCode == http.StatusOK {
+		if err := graphQLRateLimitError(resp); err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var body struct {
+			Message string `json:"message"`
+		}
+		msg := strings.TrimSpace(string(resp.Body))
+		if json.Unmarshal(resp.Body, &body) == nil && body.Message != "" {
+			msg = body.Message
+		}
+		return nil, classify(APIError{
+			Method:     method,
+			Path:       path,
+			StatusCode: resp.StatusCode,
+			Message:    msg,
+		}, resp)
+	}
+	return resp, nil
+}
+
+const maxAttempts = 4
+
+// retryable reports whether a failed request may succeed if sent again. The
+// rate tracker holds the retry back until a secondary limit has passed.
+// Rate-limited requests weren't processed, so they're retried even if not
+// idempotent; 5xx responses and network failures leave that open.
+func retryable(err error, idempotent bool) bool {
+	var rateLimited *RateLimitError
+	if errors.As(err, &rateLimited) {
+		return rateLimited.Reset.IsZero()
+	}
+	var transient *TransientError
+	var network *NetworkError
+	return idempotent && (errors.As(err, &transient) || errors.As(err, &network))
+}
+
+// graphQLRateLimitError returns a *RateLimitError if a GraphQL response's
+// errors say it was rate limited.
+func graphQLRateLimitError(resp *Response) error {
+	var envelope struct {
+		Errors []graphQLError `json:"errors"`
+	}
+	if json.Unmarshal(resp.Body, &envelope) != nil {
+		return nil
+	}
+	for _, e := range envelope.Errors {
+		if e.Type == "RATE_LIMITED" || strings.Contains(strings.ToLower(e.Message), "rate limit") {
+			return classify(APIError{Path: "graphql", Type: "RATE_LIMITED", Message: "graphql: " + e.Message}, resp)
+		}
+	}
+	return nil
+}
+
+// backoff returns 1s, 2s, 4s, ... plus up to 50% jitter.
+func backoff(attempt int) time.Duration {
+	base := time.Second << (attempt - 1)
+	return base + rand.N(base/2)
 }
 
 func normalizeChecks(nodes []checkNode) []Check {
 	var checks []Check
 	for _, n := range nodes {
-		name := n.Name
-		if name == "" {
-			name = n.Context
+		check := Check{
+			Name:       n.Name,
+			Status:     strings.ToUpper(n.Status),
+			Conclusion: strings.ToLower(n.Conclusion),
+			ID:         n.DatabaseID,
+			DetailsURL: n.DetailsURL,
 		}
-		status := n.Status
-		if status == "" {
-			status = n.State
+		if n.CheckSuite != nil && n.CheckSuite.App != nil {
+			check.App = n.CheckSuite.App.Slug
 		}
-		conclusion := n.Conclusion
-		if conclusion == "" && n.State == "SUCCESS" {
-			conclusion = "success"
-		}
-		if conclusion == "" && n.State == "FAILURE" {
-			conclusion = "failure"
+
+		// Commit statuses only have a state: SUCCESS, FAILURE, ERROR,
+		// PENDING or EXPECTED
+		if check.Name == "" {
+			check.Name = n.Context
+			check.DetailsURL = n.TargetURL
+			check.Status = "COMPLETED"
+			switch n.State {
+			case "SUCCESS":
+				check.Conclusion = ConclusionSuccess
+			case "FAILURE", "ERROR":
+				check.Conclusion = ConclusionFailure
+			default:
+				check.Status = strings.ToUpper(n.State)
+			}
 		}
-		checks = append(checks, Check{
-			Name:       name,
-			Status:     strings.ToUpper(status),
-			Conclusion: strings.ToLower(conclusion),
-		})
+
+		checks = append(checks, check)
 	}
 	return checks
 }
@@ -0,0 +1,197 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// graphQLStub answers each GraphQL query with the response of the first
+// entry whose key the query contains.
+func graphQLStub(t *testing.T, responses map[string]func(vars map[string]any) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+			return
+		}
+		for key, respond := range responses {
+			if strings.Contains(req.Query, key) {
+				_, _ = w.Write([]byte(respond(req.Variables)))
+				return
+			}
+		}
+		t.Errorf("unexpected query: %s", req.Query)
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestGetPRDetailPagesCheckContexts(t *testing.T) {
+	c := newTestClient(t, graphQLStub(t, map[string]func(map[string]any) string{
+		"pullRequest(number: $pr) { ...prFields }": func(map[string]any) string {
+			return `{"data":{"repository":{"pullRequest":{
+				"number": 1, "headRefOid": "abc",
+				"commits": {"nodes": [{"commit": {"statusCheckRollup": {"contexts": {
+					"pageInfo": {"hasNextPage": true, "endCursor": "c1"},
+					"nodes": [{"name": "build", "status": "COMPLETED", "conclusion": "SUCCESS", "databaseId": 1}]
+				}}}}]}
+			}}}}`
+		},
+		"object(oid: $sha)": func(vars map[string]any) string {
+			if vars["sha"] != "abc" {
+				t.Errorf("sha = %v, want abc", vars["sha"])
+			}
+			if vars["cursor"] == "c1" {
+				return `{"data":{"repository":{"object":{"statusCheckRollup":{"contexts":{
+					"pageInfo": {"hasNextPage": true, "endCursor": "c2"},
+					"nodes": [{"name": "test", "status": "COMPLETED", "conclusion": "FAILURE", "databaseId": 2}]
+				}}}}}}`
+			}
+			return `{"data":{"repository":{"object":{"statusCheckRollup":{"contexts":{
+				"pageInfo": {"hasNextPage": false},
+				"nodes": [{"context": "ci/legacy", "state": "PENDING"}]
+			}}}}}}`
+		},
+	}))
+
+	pr, err := c.GetPRDetail(context.Background(), "o", "r", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, check := range pr.Checks {
+		names = append(names, check.Name)
+	}
+	if got := strings.Join(names, ","); got != "build,test,ci/legacy" {
+		t.Errorf("checks = %s, want build,test,ci/legacy", got)
+	}
+}
+
+func TestGetCommentsSincePagesBackwards(t *testing.T) {
+	var cursors []any
+	c := newTestClient(t, graphQLStub(t, map[string]func(map[string]any) string{
+		"comments(last: 100, before: $cursor)": func(vars map[string]any) string {
+			cursors = append(cursors, vars["cursor"])
+			if vars["cursor"] == nil {
+				return `{"data":{"repository":{"pullRequest":{"comments":{
+					"pageInfo": {"hasPreviousPage": true, "startCursor": "p1"},
+					"nodes": [
+						{"id": "c3", "body": "three", "createdAt": "2026-01-03T00:00:00Z", "viewerDidAuthor": true},
+						{"id": "c4", "body": "four", "createdAt": "2026-01-04T00:00:00Z",
+						 "reactionGroups": [{"content": "THUMBS_UP", "viewerHasReacted": true}]}
+					]
+				}}}}}`
+			}
+			return `{"data":{"repository":{"pullRequest":{"comments":{
+				"pageInfo": {"hasPreviousPage": true, "startCursor": "p0"},
+				"nodes": [
+					{"id": "c1", "body": "one", "createdAt": "2026-01-01T00:00:00Z"},
+					{"id": "c2", "body": "two", "createdAt": "2026-01-02T00:00:00Z",
+					 "reactionGroups": [{"content": "HEART", "viewerHasReacted": true}]}
+				]
+			}}}}}`
+		},
+	}))
+
+	since := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	comments, err := c.GetCommentsSince(context.Background(), "o", "r", 1, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, cm := range comments {
+		id := cm.ID
+		if cm.Reacted {
+			id += "+"
+		}
+		if cm.ByViewer {
+			id += "*"
+		}
+		got = append(got, id)
+	}
+	// Paging stops at c1, older than since; only acknowledging reactions count
+	if strings.Join(got, ",") != "c2,c3*,c4+" {
+		t.Errorf("comments = %v, want [c2 c3* c4+]", got)
+	}
+	if len(cursors) != 2 {
+		t.Errorf("requests = %d, want 2", len(cursors))
+	}
+}
+
+func TestGetRequiredChecks(t *testing.T) {
+	tests := []struct {
+		name       string
+		rules      int // status of the rulesets response
+		protection int // status of the branch response
+		want       RequiredChecks
+	}{
+		{
+			name:  "both readable",
+			rules: http.StatusOK, protection: http.StatusOK,
+			want: RequiredChecks{Names: []string{"lint", "build", "test"}, Known: true},
+		},
+		{
+			name:  "rulesets hidden",
+			rules: http.StatusForbidden, protection: http.StatusOK,
+			want: RequiredChecks{Names: []string{"build", "test"}},
+		},
+		{
+			name:  "protection hidden",
+			rules: http.StatusOK, protection: http.StatusNotFound,
+			want: RequiredChecks{Names: []string{"lint"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.Contains(r.URL.Path, "/rules/branches/"):
+					w.WriteHeader(tt.rules)
+					if tt.rules == http.StatusOK {
+						_, _ = w.Write([]byte(`[{"type": "required_status_checks", "parameters": {"required_status_checks": [{"context": "lint"}]}}]`))
+					}
+				default:
+					w.WriteHeader(tt.protection)
+					if tt.protection == http.StatusOK {
+						_, _ = w.Write([]byte(`{"protection": {"required_status_checks": {"contexts": ["build"], "checks": [{"context": "build"}, {"context": "test"}]}}}`))
+					}
+				}
+			})
+			got, err := c.GetRequiredChecks(context.Background(), "o", "r", "main")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got.Names, ",") != strings.Join(tt.want.Names, ",") || got.Known != tt.want.Known {
+				t.Errorf("GetRequiredChecks = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkRequired(t *testing.T) {
+	tests := []struct {
+		name     string
+		required RequiredChecks
+		want     string // Required of build and test
+	}{
+		{name: "unknown", required: RequiredChecks{}, want: "true,true"},
+		{name: "known none", required: RequiredChecks{Known: true}, want: "false,false"},
+		{name: "known some", required: RequiredChecks{Names: []string{"test"}, Known: true}, want: "false,true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks := []Check{{Name: "build"}, {Name: "test"}}
+			MarkRequired(checks, tt.required)
+			if got := fmt.Sprintf("%t,%t", checks[0].Required, checks[1].Required); got != tt.want {
+				t.Errorf("Required = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
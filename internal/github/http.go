@@ -0,0 +1,126 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// DefaultAPIURL is the REST API root for github.com.
+const DefaultAPIURL = "https://api.github.com/"
+
+// TokenSource supplies the bearer token for API requests.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a fixed token.
+type StaticToken string
+
+func (t StaticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// EnvToken reads the token from the named environment variable on each request.
+type EnvToken string
+
+func (e EnvToken) Token(context.Context) (string, error) {
+	token := os.Getenv(string(e))
+	if token == "" {
+		return "", fmt.Errorf("environment variable %s is empty", string(e))
+	}
+	return token, nil
+}
+
+// HTTPTransport talks to the REST and GraphQL APIs directly over HTTP.
+type HTTPTransport struct {
+	baseURL    *url.URL
+	graphQLURL string
+	tokens     TokenSource
+	client     *http.Client
+}
+
+// NewHTTPTransport creates a transport rooted at baseURL (e.g.
+// https://api.github.com/ or https://ghe.example.com/api/v3/). A nil client
+// uses http.DefaultClient.
+func NewHTTPTransport(baseURL string, tokens TokenSource, client *http.Client) (*HTTPTransport, error) {
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse API URL %q: %w", baseURL, err)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	// GHES serves REST under /api/v3/ and GraphQL under /api/graphql
+	graphQLURL := u.String() + "graphql"
+	if strings.HasSuffix(u.Path, "/api/v3/") {
+		gu := *u
+		gu.Path = strings.TrimSuffix(u.Path, "v3/") + "graphql"
+		graphQLURL = gu.String()
+	}
+
+	return &HTTPTransport{
+		baseURL:    u,
+		graphQLURL: graphQLURL,
+		tokens:     tokens,
+		client:     client,
+	}, nil
+}
+
+func (t *HTTPTransport) Do(ctx context.Context, method, path string, body []byte) (*Response, error) {
+	target := t.graphQLURL
+	if path != "graphql" {
+		ref, err := url.Parse(strings.TrimPrefix(path, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("parse path %q: %w", path, err)
+		}
+		target = t.baseURL.ResolveReference(ref).String()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if t.tokens != nil {
+		token, err := t.tokens.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, &NetworkError{Err: err}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &NetworkError{Err: fmt.Errorf("read response: %w", err)}
+	}
+
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       data,
+	}, nil
+}
@@ -0,0 +1,336 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	transport, err := NewHTTPTransport(srv.URL, StaticToken("secret"), srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(transport, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	c.backoff = func(int) time.Duration { return 0 }
+	return c
+}
+
+func TestHTTPTransportRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     string // appended to the server URL
+		path     string
+		wantPath string
+	}{
+		{name: "rest", base: "/", path: "repos/o/r/pulls/1", wantPath: "/repos/o/r/pulls/1"},
+		{name: "rest leading slash", base: "", path: "/repos/o/r", wantPath: "/repos/o/r"},
+		{name: "graphql", base: "/", path: "graphql", wantPath: "/graphql"},
+		{name: "ghes rest", base: "/api/v3", path: "repos/o/r", wantPath: "/api/v3/repos/o/r"},
+		{name: "ghes graphql", base: "/api/v3/", path: "graphql", wantPath: "/api/graphql"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			var body []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+				body, _ = io.ReadAll(r.Body)
+				w.Header().Set("X-Test", "yes")
+				w.WriteHeader(http.StatusTeapot)
+				_, _ = w.Write([]byte(`{"message":"short and stout"}`))
+			}))
+			defer srv.Close()
+
+			transport, err := NewHTTPTransport(srv.URL+tt.base, StaticToken("secret"), srv.Client())
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := transport.Do(context.Background(), http.MethodPost, tt.path, []byte(`{"a":1}`))
+			if err != nil {
+				t.Fatalf("Do: %v", err)
+			}
+
+			if got.URL.Path != tt.wantPath {
+				t.Errorf("path = %q, want %q", got.URL.Path, tt.wantPath)
+			}
+			if h := got.Header.Get("Authorization"); h != "Bearer secret" {
+				t.Errorf("Authorization = %q", h)
+			}
+			if h := got.Header.Get("Accept"); h != "application/vnd.github+json" {
+				t.Errorf("Accept = %q", h)
+			}
+			if h := got.Header.Get("Content-Type"); h != "application/json" {
+				t.Errorf("Content-Type = %q", h)
+			}
+			if string(body) != `{"a":1}` {
+				t.Errorf("body = %q", body)
+			}
+			// Non-2xx responses aren't transport errors
+			if resp.StatusCode != http.StatusTeapot || resp.Header.Get("X-Test") != "yes" || string(resp.Body) != `{"message":"short and stout"}` {
+				t.Errorf("response = %d %v %q", resp.StatusCode, resp.Header, resp.Body)
+			}
+		})
+	}
+}
+
+func TestHTTPTransportNetworkError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	transport, err := NewHTTPTransport(srv.URL, nil, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	_, err = transport.Do(context.Background(), http.MethodGet, "repos/o/r", nil)
+	var network *NetworkError
+	if !errors.As(err, &network) {
+		t.Fatalf("err = %v, want *NetworkError", err)
+	}
+}
+
+// resetConnection closes the connection without a response.
+func resetConnection(w http.ResponseWriter) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err == nil {
+		_ = conn.Close()
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	rateLimitReset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		failures     int // responses answered by fail before succeeding
+		fail         func(w http.ResponseWriter)
+		wantRequests int
+		wantErr      any // pointer to the expected error type, nil for success
+	}{
+		{
+			name:   "5xx get is retried",
+			method: http.MethodGet, path: "repos/o/r",
+			failures: 2,
+			fail: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			wantRequests: 3,
+		},
+		{
+			name:   "5xx post is not retried",
+			method: http.MethodPost, path: "repos/o/r/issues/1/comments",
+			failures: 1,
+			fail: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			wantRequests: 1,
+			wantErr:      new(*TransientError),
+		},
+		{
+			name:   "5xx gives up after max attempts",
+			method: http.MethodGet, path: "repos/o/r",
+			failures: maxAttempts,
+			fail: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			wantRequests: maxAttempts,
+			wantErr:      new(*TransientError),
+		},
+		{
+			name:   "connection reset on get is retried",
+			method: http.MethodGet, path: "repos/o/r",
+			failures:     1,
+			fail:         resetConnection,
+			wantRequests: 2,
+		},
+		{
+			name:   "connection reset on post is not retried",
+			method: http.MethodPost, path: "repos/o/r/issues/1/comments",
+			failures:     1,
+			fail:         resetConnection,
+			wantRequests: 1,
+			wantErr:      new(*NetworkError),
+		},
+		{
+			name:   "secondary limit is retried even for post",
+			method: http.MethodPost, path: "repos/o/r/issues/1/comments",
+			failures: 1,
+			fail: func(w http.ResponseWriter) {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			wantRequests: 2,
+		},
+		{
+			name:   "exhausted primary limit fails fast",
+			method: http.MethodGet, path: "repos/o/r",
+			failures: 1,
+			fail: func(w http.ResponseWriter) {
+				w.Header().Set("X-RateLimit-Limit", "5000")
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", rateLimitReset)
+				w.WriteHeader(http.StatusForbidden)
+			},
+			wantRequests: 1,
+			wantErr:      new(*RateLimitError),
+		},
+		{
+			name:   "not found is not retried",
+			method: http.MethodGet, path: "repos/o/r",
+			failures: 1,
+			fail: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			wantRequests: 1,
+			wantErr:      new(*NotFoundError),
+		},
+		{
+			name:   "graphql rate limit error is retried",
+			method: http.MethodPost, path: "graphql",
+			failures: 1,
+			fail: func(w http.ResponseWriter) {
+				_, _ = w.Write([]byte(`{"errors":[{"type":"RATE_LIMITED","message":"API rate limit exceeded"}]}`))
+			},
+			wantRequests: 2,
+		},
+		{
+			name:   "graphql rate limit with exhausted quota fails fast",
+			method: http.MethodPost, path: "graphql",
+			failures: 1,
+			fail: func(w http.ResponseWriter) {
+				w.Header().Set("X-RateLimit-Limit", "5000")
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", rateLimitReset)
+				w.Header().Set("X-RateLimit-Resource", "graphql")
+				_, _ = w.Write([]byte(`{"errors":[{"type":"RATE_LIMITED","message":"API rate limit exceeded"}]}`))
+			},
+			wantRequests: 1,
+			wantErr:      new(*RateLimitError),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if int(requests.Add(1)) <= tt.failures {
+					tt.fail(w)
+					return
+				}
+				_, _ = w.Write([]byte(`{"data":{}}`))
+			})
+
+			var err error
+			if tt.path == "graphql" {
+				err = c.graphQL(context.Background(), "query { viewer { login } }", nil, nil)
+			} else {
+				err = c.rest(context.Background(), tt.method, tt.path, nil, nil)
+			}
+
+			if got := int(requests.Load()); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+			switch target := tt.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Errorf("err = %v, want nil", err)
+				}
+			default:
+				if err == nil || !errors.As(err, target) {
+					t.Errorf("err = %v (%T), want %T", err, err, target)
+				}
+			}
+		})
+	}
+}
+
+func TestGraphQLMutationRetriedOnlyWhenRateLimited(t *testing.T) {
+	tests := []struct {
+		name         string
+		fail         func(w http.ResponseWriter)
+		wantRequests int
+	}{
+		{
+			name: "rate limited",
+			fail: func(w http.ResponseWriter) {
+				_, _ = w.Write([]byte(`{"errors":[{"type":"RATE_LIMITED","message":"API rate limit exceeded"}]}`))
+			},
+			wantRequests: 2,
+		},
+		{
+			name: "bad gateway",
+			fail: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			wantRequests: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if requests.Add(1) == 1 {
+					tt.fail(w)
+					return
+				}
+				_, _ = w.Write([]byte(`{"data":{}}`))
+			})
+			_ = c.graphQL(context.Background(), "mutation { noop }", nil, nil)
+			if got := int(requests.Load()); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestClientDoesNotRetryCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var requests atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		cancel()
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	if err := c.rest(ctx, http.MethodGet, "repos/o/r", nil, nil); err == nil {
+		t.Fatal("err = nil")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 1; attempt <= 4; attempt++ {
+		base := time.Second << (attempt - 1)
+		for range 20 {
+			if d := backoff(attempt); d < base || d >= base+base/2 {
+				t.Fatalf("backoff(%d) = %v, want in [%v, %v)", attempt, d, base, base+base/2)
+			}
+		}
+	}
+}
+
+func TestRateTrackerWaitsForRetryAfter(t *testing.T) {
+	tracker := newRateTracker()
+	tracker.observe(&Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"1"}},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := tracker.wait(ctx, "core"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait = %v, want to block past the deadline", err)
+	}
+}
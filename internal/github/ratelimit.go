@@ -0,0 +1,181 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// secondaryLimitWait is how long to back off after a secondary rate limit
+// response without Retry-After, per GitHub's guidance.
+const secondaryLimitWait = time.Minute
+
+// RateLimit is the last known quota for one API resource ("core", "graphql").
+type RateLimit struct {
+	Resource  string
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// Known reports whether any response has reported this quota yet.
+func (r RateLimit) Known() bool {
+	return r.Limit > 0
+}
+
+// Exhausted reports whether no requests are left until Reset.
+func (r RateLimit) Exhausted() bool {
+	return r.Known() && r.Remaining <= 0 && time.Now().Before(r.Reset)
+}
+
+// Low reports whether less than 10% of the quota is left.
+func (r RateLimit) Low() bool {
+	return r.Known() && r.Remaining*10 < r.Limit
+}
+
+// PollFactor is how many poll intervals to stretch polling over so the
+// remaining quota lasts until reset: 1 with plenty left, up to 4 when low.
+func (r RateLimit) PollFactor() int {
+	switch {
+	case !r.Known():
+		return 1
+	case r.Low():
+		return 4
+	case r.Remaining*4 < r.Limit:
+		return 2
+	default:
+		return 1
+	}
+}
+
+func (r RateLimit) fraction() float64 {
+	if !r.Known() {
+		return 1
+	}
+	return float64(r.Remaining) / float64(r.Limit)
+}
+
+// rateTracker records quota from response headers and holds requests back
+// while a limit is in effect.
+type rateTracker struct {
+	mu         sync.Mutex
+	limits     map[string]RateLimit // key: resource
+	retryAfter time.Time            // secondary limit backoff
+}
+
+func newRateTracker() *rateTracker {
+	return &rateTracker{limits: make(map[string]RateLimit)}
+}
+
+// wait blocks while a secondary limit is in effect and fails fast when the
+// primary quota for resource is exhausted.
+func (t *rateTracker) wait(ctx context.Context, resource string) error {
+	t.mu.Lock()
+	limit := t.limits[resource]
+	until := t.retryAfter
+	t.mu.Unlock()
+
+	if limit.Exhausted() {
+		return &RateLimitError{
+			APIError: APIError{
+				Type:    "RATE_LIMITED",
+				Message: fmt.Sprintf("%s rate limit exhausted until %s", resource, limit.Reset.Format(time.TimeOnly)),
+			},
+			Reset: limit.Reset,
+		}
+	}
+
+	if d := time.Until(until); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return nil
+}
+
+func (t *rateTracker) observe(resp *Response) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if limit, ok := parseRateLimit(resp.Header); ok {
+		t.limits[limit.Resource] = limit
+	}
+
+	if isSecondaryLimit(resp) {
+		wait := secondaryLimitWait
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			wait = time.Duration(secs) * time.Second
+		}
+		t.retryAfter = time.Now().Add(wait)
+	}
+}
+
+// isSecondaryLimit matches abuse/secondary limit responses, which don't touch
+// the primary quota headers.
+func isSecondaryLimit(resp *Response) bool {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		return resp.Header.Get("Retry-After") != "" ||
+			bytes.Contains(bytes.ToLower(resp.Body), []byte("secondary rate limit"))
+	default:
+		return false
+	}
+}
+
+// lowest returns the most constrained known quota.
+func (t *rateTracker) lowest() RateLimit {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var lowest RateLimit
+	for _, l := range t.limits {
+		lowest = LowestRateLimit(lowest, l)
+	}
+	return lowest
+}
+
+// LowestRateLimit returns the most constrained of the known quotas in limits.
+func LowestRateLimit(limits ...RateLimit) RateLimit {
+	var lowest RateLimit
+	for _, l := range limits {
+		if l.Known() && (!lowest.Known() || l.fraction() < lowest.fraction()) {
+			lowest = l
+		}
+	}
+	return lowest
+}
+
+func parseRateLimit(h http.Header) (RateLimit, bool) {
+	limit, err := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+	reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return RateLimit{}, false
+	}
+	resource := h.Get("X-RateLimit-Resource")
+	if resource == "" {
+		resource = "core"
+	}
+	return RateLimit{
+		Resource:  resource,
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Unix(reset, 0),
+	}, true
+}
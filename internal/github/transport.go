@@ -0,0 +1,132 @@
+package github
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/textproto"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Transport sends a single request to the GitHub API. Path is relative to the
+// REST API root, except for "graphql" which addresses the GraphQL endpoint.
+// Non-2xx responses are returned as a Response, not as an error; errors are
+// reserved for failures to get any response at all.
+type Transport interface {
+	Do(ctx context.Context, method, path string, body []byte) (*Response, error)
+}
+
+// Response is a raw GitHub API response.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// GHTransport talks to the API through `gh api`. Without a TokenSource it
+// reuses whatever auth the gh CLI is logged in with for its host.
+type GHTransport struct {
+	host   string
+	tokens TokenSource
+	logger *slog.Logger
+}
+
+// NewGHTransport creates a transport for host (e.g. github.com or a GHES
+// hostname); empty uses gh's default host. A non-nil tokens overrides gh's
+// stored credentials.
+func NewGHTransport(host string, tokens TokenSource, logger *slog.Logger) *GHTransport {
+	return &GHTransport{host: host, tokens: tokens, logger: logger}
+}
+
+func (t *GHTransport) Do(ctx context.Context, method, path string, body []byte) (*Response, error) {
+	args := []string{"api", "--include", "--method", method, path}
+	if t.host != "" {
+		args = append(args, "--hostname", t.host)
+	}
+	if body != nil {
+		args = append(args, "--input", "-")
+	}
+
+	t.logger.Debug("gh", "args", strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	if t.tokens != nil {
+		token, err := t.tokens.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get token: %w", err)
+		}
+		// gh reads GH_ENTERPRISE_TOKEN for GHES hosts
+		envVar := "GH_TOKEN"
+		if t.host != "" && t.host != "github.com" {
+			envVar = "GH_ENTERPRISE_TOKEN"
+		}
+		cmd.Env = append(os.Environ(), envVar+"="+token)
+	}
+	if body != nil {
+		cmd.Stdin = bytes.NewReader(body)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	resp, parseErr := parseGHOutput(out)
+	if err != nil {
+		// gh exits non-zero on HTTP errors but still prints the response,
+		// which carries more detail than its stderr summary.
+		if parseErr == nil {
+			return resp, nil
+		}
+		// No response at all, e.g. a connection failure
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(out) == 0 {
+			return nil, &NetworkError{Err: fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))}
+		}
+		return nil, fmt.Errorf("%w: %s", err, stderr.String())
+	}
+	if parseErr != nil {
+		return nil, fmt.Errorf("parse gh output: %w", parseErr)
+	}
+	return resp, nil
+}
+
+// parseGHOutput splits `gh api --include` output into status, headers and body.
+func parseGHOutput(out []byte) (*Response, error) {
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(out)))
+
+	statusLine, err := r.ReadLine()
+	if err != nil {
+		return nil, fmt.Errorf("read status line: %w", err)
+	}
+	// e.g. "HTTP/2.0 200 OK"
+	fields := strings.Fields(statusLine)
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "HTTP/") {
+		return nil, fmt.Errorf("unexpected status line %q", statusLine)
+	}
+	code, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("parse status code %q: %w", fields[1], err)
+	}
+
+	header, err := r.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("read headers: %w", err)
+	}
+
+	body, err := io.ReadAll(r.R)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+
+	return &Response{
+		StatusCode: code,
+		Header:     http.Header(header),
+		Body:       body,
+	}, nil
+}
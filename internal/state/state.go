@@ -0,0 +1,130 @@
+// Package state persists per-PR settings made through PR comment commands,
+// such as a pause, which comments were checked for commands and which state
+// labels were added, so they survive daemon restarts.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// PR is the persisted state of one PR.
+type PR struct {
+	Paused      bool   `json:"paused,omitempty"`
+	MergeMethod string `json:"merge_method,omitempty"` // overrides the repo's merge_method
+
+	// The newest comment checked for commands. Later comments are new.
+	LastCommentID string    `json:"last_comment_id,omitempty"`
+	LastCommentAt time.Time `json:"last_comment_at,omitzero"`
+
+	Labels []string `json:"labels,omitempty"` // state labels auto-claude added, see config.StateLabelsConfig
+}
+
+// Store is a JSON file of PR states keyed by host/owner/repo#number. All methods
+// are safe for concurrent use.
+type Store struct {
+	path string
+
+	mu  sync.Mutex
+	prs map[string]PR
+}
+
+// Open loads the store at path. A missing file is an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, prs: make(map[string]PR)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read state: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.prs); err != nil {
+		return nil, fmt.Errorf("parse state %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Get returns the state of a PR; the zero PR if none was stored.
+func (s *Store) Get(key string) PR {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.prs[key]
+}
+
+// Update changes the state of a PR and saves the store. Zero states are
+// dropped.
+func (s *Store) Update(key string, update func(*PR)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pr := s.prs[key]
+	update(&pr)
+	if reflect.ValueOf(pr).IsZero() {
+		delete(s.prs, key)
+	} else {
+		s.prs[key] = pr
+	}
+	return s.save()
+}
+
+// Prune drops the states of keys keep rejects, e.g. those of closed PRs.
+func (s *Store) Prune(keep func(key string) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pruned := false
+	for key := range s.prs {
+		if !keep(key) {
+			delete(s.prs, key)
+			pruned = true
+		}
+	}
+	if !pruned {
+		return nil
+	}
+	return s.save()
+}
+
+// Rename moves each state to the key rename returns for its key, e.g. to
+// migrate keys of an older format.
+func (s *Store) Rename(rename func(key string) string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	renamed := false
+	for key, pr := range s.prs {
+		if to := rename(key); to != key {
+			delete(s.prs, key)
+			s.prs[to] = pr
+			renamed = true
+		}
+	}
+	if !renamed {
+		return nil
+	}
+	return s.save()
+}
+
+// save writes the store through a temporary file, so a crash never leaves
+// it half written.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.prs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("create state dir: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+	return nil
+}
@@ -7,9 +7,20 @@ type Snapshot struct {
 	Repos          []RepoState
 	ClaudeSessions []ClaudeSessionState
 	WorkerCount    int
+	RateLimit      RateLimitState
+}
+
+// RateLimitState is the most constrained GitHub API quota. Limit is 0 until
+// the first response reports it.
+type RateLimitState struct {
+	Resource  string
+	Remaining int
+	Limit     int
+	Reset     time.Time
 }
 
 type RepoState struct {
+	Host       string
 	Owner      string
 	Name       string
 	PRs        []PRState
@@ -18,11 +29,12 @@ type RepoState struct {
 }
 
 type PRState struct {
-	Number    int
-	Title     string
-	States    []string // draft|conflicting|checks_failing|checks_pending|copilot_pending|reviews_pending|ready
-	Author    string
-	HasWorker bool
+	Number        int
+	Title         string
+	States        []string // draft|conflicting|checks_failing[:<category>]|checks_pending|bot_review_pending:<bot>|fixing_reviews:<bot>|awaiting_human:<bot>|reviews_pending|ready|merge_queued|auto_merge_pending|dequeued|checks_rerun|checks_blocked|optional_checks_failing|gave_up|paused
+	Author        string
+	HasWorker     bool
+	QueuePosition int // 1-based merge queue position, 0 when not queued
 }
 
 type ClaudeSessionState struct {
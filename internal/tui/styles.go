@@ -12,10 +12,12 @@ var (
 	colorConflicting    = lipgloss.Color("220") // yellow
 	colorChecksFailing  = lipgloss.Color("196") // red
 	colorChecksPending  = lipgloss.Color("33")  // blue
-	colorCopilotPending = lipgloss.Color("135") // purple
+	colorBotPending     = lipgloss.Color("135") // purple
 	colorFixingReviews  = lipgloss.Color("208") // orange-red
 	colorReviewsPending = lipgloss.Color("214") // orange
 	colorReady          = lipgloss.Color("46")  // green
+	colorMergeQueued    = lipgloss.Color("42")  // teal
+	colorDequeued       = lipgloss.Color("160") // dark red
 
 	// Styles
 	headerStyle = lipgloss.NewStyle().
@@ -59,20 +61,38 @@ func stateIcon(state string) string {
 	switch state {
 	case "draft":
 		return "📝"
+	case "paused":
+		return "⏸️"
 	case "conflicting":
 		return "⚠️"
 	case "checks_failing":
 		return "🔨"
 	case "checks_pending":
 		return "⚙️"
-	case "copilot_pending":
+	case "checks_rerun":
+		return "🔁"
+	case "checks_blocked":
+		return "🚧"
+	case "optional_checks_failing":
+		return "ℹ️"
+	case "bot_review_pending":
 		return "🤖"
 	case "fixing_reviews":
 		return "🔧"
 	case "reviews_pending":
 		return "📋"
+	case "awaiting_human":
+		return "🙋"
 	case "ready":
 		return "✅"
+	case "merge_queued":
+		return "🚂"
+	case "auto_merge_pending":
+		return "⏳"
+	case "dequeued":
+		return "⛔"
+	case "gave_up":
+		return "🛑"
 	default:
 		return "❓"
 	}
@@ -80,22 +100,30 @@ func stateIcon(state string) string {
 
 func stateColor(state string) color.Color {
 	switch state {
-	case "draft":
+	case "draft", "paused":
 		return colorDraft
 	case "conflicting":
 		return colorConflicting
 	case "checks_failing":
 		return colorChecksFailing
-	case "checks_pending":
+	case "checks_pending", "checks_rerun":
 		return colorChecksPending
-	case "copilot_pending":
-		return colorCopilotPending
+	case "checks_blocked":
+		return colorConflicting
+	case "optional_checks_failing":
+		return colorDraft
+	case "bot_review_pending":
+		return colorBotPending
 	case "fixing_reviews":
 		return colorFixingReviews
-	case "reviews_pending":
+	case "reviews_pending", "awaiting_human":
 		return colorReviewsPending
 	case "ready":
 		return colorReady
+	case "merge_queued", "auto_merge_pending":
+		return colorMergeQueued
+	case "dequeued", "gave_up":
+		return colorDequeued
 	default:
 		return lipgloss.Color("252")
 	}
@@ -19,6 +19,10 @@ func renderListView(snap Snapshot, selectedSession int) string {
 	}
 	header := fmt.Sprintf("auto-claude │ %d repos │ %d PRs │ %d workers",
 		len(snap.Repos), prCount, snap.WorkerCount)
+	if rl := snap.RateLimit; rl.Limit > 0 {
+		header += fmt.Sprintf(" │ API %s %d/%d (resets %s)",
+			rl.Resource, rl.Remaining, rl.Limit, rl.Reset.Format("15:04"))
+	}
 	b.WriteString(headerStyle.Render(header))
 	b.WriteString("\n")
 
@@ -115,8 +119,12 @@ func renderTree(repos []RepoState) string {
 		if repo.BlockedPRs > 0 {
 			blockedInfo = fmt.Sprintf(" │ %d blocked", repo.BlockedPRs)
 		}
-		repoLine := fmt.Sprintf("%s 🔧 %s/%s [%d workers │ %d PRs%s]",
-			prefix, repo.Owner, repo.Name, repo.Workers, len(repo.PRs), blockedInfo)
+		name := repo.Owner + "/" + repo.Name
+		if repo.Host != "" && repo.Host != "github.com" {
+			name = repo.Host + "/" + name
+		}
+		repoLine := fmt.Sprintf("%s 🔧 %s [%d workers │ %d PRs%s]",
+			prefix, name, repo.Workers, len(repo.PRs), blockedInfo)
 		b.WriteString(treeRepoStyle.Render(repoLine))
 		b.WriteString("\n")
 
@@ -161,8 +169,18 @@ func renderTree(repos []RepoState) string {
 			// Status lines (nested under PR)
 			for k, state := range pr.States {
 				isLastState := k == len(pr.States)-1
-				icon := stateIcon(state)
-				color := stateColor(state)
+				// States may carry a detail, e.g. "checks_failing:lint"
+				kind, detail, _ := strings.Cut(state, ":")
+				icon := stateIcon(kind)
+				color := stateColor(kind)
+
+				label := kind
+				if detail != "" {
+					label = fmt.Sprintf("%s (%s)", kind, detail)
+				}
+				if state == "merge_queued" && pr.QueuePosition > 0 {
+					label = fmt.Sprintf("%s #%d", state, pr.QueuePosition)
+				}
 
 				claudeIndicator := ""
 				if pr.HasWorker && isLastState {
@@ -180,7 +198,7 @@ func renderTree(repos []RepoState) string {
 				}
 
 				statusLine := fmt.Sprintf("%s   %s %s %s%s",
-					statusPrefix, statePrefix, icon, state, claudeIndicator)
+					statusPrefix, statePrefix, icon, label, claudeIndicator)
 				b.WriteString(lipgloss.NewStyle().Foreground(color).Render(statusLine))
 				b.WriteString("\n")
 			}
@@ -0,0 +1,177 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// maxPayloadBytes matches GitHub's cap on webhook payloads.
+const maxPayloadBytes = 25 << 20
+
+// Event is a webhook delivery reduced to what the daemon acts on.
+type Event struct {
+	Host      string // github.com, or the X-GitHub-Enterprise-Host header on GHES
+	Type      string // X-GitHub-Event header, e.g. "check_suite"
+	Action    string
+	Owner     string
+	Repo      string
+	PRNumbers []int  // empty when the payload doesn't name a PR
+	Label     string // the label added or removed by "labeled" and "unlabeled" actions
+}
+
+// Handler verifies GitHub webhook deliveries and passes supported events to
+// onEvent. onEvent must not block; deliveries are acknowledged before the
+// daemon acts on them.
+type Handler struct {
+	secret  []byte
+	onEvent func(Event)
+	logger  *slog.Logger
+}
+
+func NewHandler(secret []byte, onEvent func(Event), logger *slog.Logger) *Handler {
+	return &Handler{secret: secret, onEvent: onEvent, logger: logger}
+}
+
+var supportedEvents = map[string]bool{
+	"pull_request":               true,
+	"pull_request_review":        true,
+	"pull_request_review_thread": true,
+	"check_suite":                true,
+	"check_run":                  true,
+	"issue_comment":              true, // comment commands
+}
+
+type payload struct {
+	Action     string `json:"action"`
+	Repository struct {
+		Name  string `json:"name"`
+		Owner struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	} `json:"repository"`
+	PullRequest *prRef `json:"pull_request"`
+	Label       *struct {
+		Name string `json:"name"`
+	} `json:"label"`
+	CheckSuite *struct {
+		PullRequests []prRef `json:"pull_requests"`
+	} `json:"check_suite"`
+	CheckRun *struct {
+		PullRequests []prRef `json:"pull_requests"`
+	} `json:"check_run"`
+	Issue *struct {
+		Number      int       `json:"number"`
+		PullRequest *struct{} `json:"pull_request"` // set when the issue is a PR
+	} `json:"issue"`
+}
+
+type prRef struct {
+	Number int `json:"number"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPayloadBytes+1))
+	if err != nil {
+		http.Error(w, "read body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxPayloadBytes {
+		http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if !h.validSignature(r.Header.Get("X-Hub-Signature-256"), body) {
+		h.logger.Warn("webhook signature mismatch", "delivery", r.Header.Get("X-GitHub-Delivery"))
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	eventType := r.Header.Get("X-GitHub-Event")
+	if !supportedEvents[eventType] {
+		// Includes "ping", sent when the hook is created
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var p payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	host := r.Header.Get("X-GitHub-Enterprise-Host")
+	if host == "" {
+		host = "github.com"
+	}
+
+	evt := Event{
+		Host:   host,
+		Type:   eventType,
+		Action: p.Action,
+		Owner:  p.Repository.Owner.Login,
+		Repo:   p.Repository.Name,
+	}
+	if p.Label != nil {
+		evt.Label = p.Label.Name
+	}
+	switch {
+	case p.PullRequest != nil:
+		evt.PRNumbers = []int{p.PullRequest.Number}
+	case p.CheckSuite != nil:
+		evt.PRNumbers = prNumbers(p.CheckSuite.PullRequests)
+	case p.CheckRun != nil:
+		evt.PRNumbers = prNumbers(p.CheckRun.PullRequests)
+	case p.Issue != nil:
+		if p.Issue.PullRequest == nil {
+			// Comments on plain issues
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		evt.PRNumbers = []int{p.Issue.Number}
+	}
+
+	h.logger.Debug("webhook received",
+		"event", evt.Type,
+		"action", evt.Action,
+		"repo", evt.Owner+"/"+evt.Repo,
+		"host", evt.Host,
+		"prs", evt.PRNumbers,
+		"delivery", r.Header.Get("X-GitHub-Delivery"))
+	h.onEvent(evt)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// validSignature checks the "sha256=<hex>" HMAC GitHub computes over the body.
+func (h *Handler) validSignature(header string, body []byte) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+func prNumbers(refs []prRef) []int {
+	numbers := make([]int, 0, len(refs))
+	for _, ref := range refs {
+		numbers = append(numbers, ref.Number)
+	}
+	return numbers
+}
@@ -2,12 +2,12 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
-	"regexp"
-	"strconv"
 	"strings"
+
+	"github.com/marcin-skalski/auto-claude/internal/config"
+	"github.com/marcin-skalski/auto-claude/internal/github"
 )
 
 func (w *Worker) resolveConflicts(ctx context.Context, wtDir string) error {
@@ -22,17 +22,7 @@ func (w *Worker) resolveConflicts(ctx context.Context, wtDir string) error {
 		w.repo.BaseBranch, w.repo.BaseBranch,
 	)
 
-	w.onClaudeStart("resolving_conflicts")
-	endCalled := false
-	defer func() {
-		if !endCalled {
-			w.onClaudeEnd()
-		}
-	}()
-
-	result, err := w.claude.RunWithCallback(ctx, wtDir, prompt, w.onClaudeOutput)
-	w.onClaudeEnd()
-	endCalled = true
+	result, err := w.runClaude(ctx, wtDir, "resolving_conflicts", prompt)
 	if err != nil {
 		return fmt.Errorf("claude resolve conflicts: %w", err)
 	}
@@ -50,7 +40,7 @@ func (w *Worker) resolveConflicts(ctx context.Context, wtDir string) error {
 		return fmt.Errorf("no commits created by claude, cannot push")
 	}
 
-	if err := w.git.Push(ctx, wtDir, w.pr.HeadRef); err != nil {
+	if err := w.push(ctx, wtDir); err != nil {
 		return fmt.Errorf("push: %w", err)
 	}
 
@@ -58,36 +48,24 @@ func (w *Worker) resolveConflicts(ctx context.Context, wtDir string) error {
 	return nil
 }
 
-func (w *Worker) fixChecks(ctx context.Context, wtDir string) error {
-	var failing []string
-	for _, c := range w.pr.Checks {
-		if c.Conclusion == "failure" {
-			failing = append(failing, c.Name)
-		}
+func (w *Worker) fixChecks(ctx context.Context, wtDir string, failing []github.Check) error {
+	var names []string
+	for _, c := range failing {
+		names = append(names, c.Name)
 	}
 
-	w.logger.Info("fixing failing checks", "checks", failing)
+	w.logger.Info("fixing failing checks", "checks", names)
 
 	if err := w.git.Fetch(ctx, wtDir); err != nil {
 		return fmt.Errorf("fetch: %w", err)
 	}
 
 	prompt := fmt.Sprintf(
-		"CI checks failing: %s. Investigate failures, fix code, commit with -s -S flags. Before pushing, run these checks and confirm each passes: `golangci-lint run`, `go test ./...`, `go build ./cmd/auto-claude/`.",
-		strings.Join(failing, ", "),
+		"CI checks failing:\n%s\nInvestigate failures, fix code, commit with -s -S flags. Before pushing, run these checks and confirm each passes: `golangci-lint run`, `go test ./...`, `go build ./cmd/auto-claude/`.",
+		w.describeFailingChecks(ctx, wtDir, failing),
 	)
 
-	w.onClaudeStart("fixing_checks")
-	endCalled := false
-	defer func() {
-		if !endCalled {
-			w.onClaudeEnd()
-		}
-	}()
-
-	result, err := w.claude.RunWithCallback(ctx, wtDir, prompt, w.onClaudeOutput)
-	w.onClaudeEnd()
-	endCalled = true
+	result, err := w.runClaude(ctx, wtDir, "fixing_checks", prompt)
 	if err != nil {
 		return fmt.Errorf("claude fix checks: %w", err)
 	}
@@ -105,7 +83,7 @@ func (w *Worker) fixChecks(ctx context.Context, wtDir string) error {
 		return fmt.Errorf("no commits created by claude, cannot push")
 	}
 
-	if err := w.git.Push(ctx, wtDir, w.pr.HeadRef); err != nil {
+	if err := w.push(ctx, wtDir); err != nil {
 		return fmt.Errorf("push: %w", err)
 	}
 
@@ -113,184 +91,56 @@ func (w *Worker) fixChecks(ctx context.Context, wtDir string) error {
 	return nil
 }
 
-type reviewFixSummary struct {
-	Total        int
-	Applied      int
-	Questionable int
-	Invalid      int
-}
-
-func parseReviewFixSummary(output string) (*reviewFixSummary, error) {
-	// Expected format:
-	// Summary: Fixed N/M unresolved review comments
-	// Applied:
-	// ✓ N valid fixes across K files
-	// Skipped:
-	// ? X questionable
-	// ✗ Y invalid
-
-	summary := &reviewFixSummary{}
-
-	// Parse "Fixed N/M unresolved review comments"
-	summaryRe := regexp.MustCompile(`Fixed (\d+)/(\d+) unresolved review comments`)
-	if matches := summaryRe.FindStringSubmatch(output); len(matches) == 3 {
-		applied, _ := strconv.Atoi(matches[1])
-		total, _ := strconv.Atoi(matches[2])
-		summary.Applied = applied
-		summary.Total = total
-	} else {
-		return nil, fmt.Errorf("could not parse summary line")
-	}
-
-	// Parse "? X questionable"
-	questionableRe := regexp.MustCompile(`\? (\d+) questionable`)
-	if matches := questionableRe.FindStringSubmatch(output); len(matches) == 2 {
-		summary.Questionable, _ = strconv.Atoi(matches[1])
+// push pushes the PR branch, remembering the commits it pushed for the
+// status comment.
+func (w *Worker) push(ctx context.Context, wtDir string) error {
+	commits, err := w.git.UnpushedCommits(ctx, wtDir, w.pr.HeadRef)
+	if err != nil {
+		return fmt.Errorf("list unpushed commits: %w", err)
 	}
-
-	// Parse "✗ Y invalid"
-	invalidRe := regexp.MustCompile(`✗ (\d+) invalid`)
-	if matches := invalidRe.FindStringSubmatch(output); len(matches) == 2 {
-		summary.Invalid, _ = strconv.Atoi(matches[1])
+	if err := w.git.Push(ctx, wtDir, w.pr.HeadRef); err != nil {
+		return err
 	}
-
-	return summary, nil
+	w.pushed = append(w.pushed, commits...)
+	return nil
 }
 
-func (w *Worker) fixReviews(ctx context.Context, wtDir string) error {
-	w.logger.Info("fixing review comments")
-
-	// Collect unresolved Copilot review threads
-	var unresolvedThreads []string
-	for _, t := range w.cachedReviewThreads {
-		if t.IsResolved || t.IsOutdated {
+// rerunChecks re-runs the checks whose conclusion is configured to be re-run.
+func (w *Worker) rerunChecks(ctx context.Context) error {
+	for _, c := range w.gatingChecks() {
+		if !c.Completed() || w.checkAction(c) != config.CheckRerun {
 			continue
 		}
-		for _, c := range t.Comments {
-			if isCopilotAuthor(c.Author) {
-				unresolvedThreads = append(unresolvedThreads, t.ID)
-				break
-			}
+		w.logger.Info("re-running check", "check", c.Name, "conclusion", c.Conclusion)
+		if err := w.gh.RerunCheck(ctx, w.repo.Owner, w.repo.Name, c); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	if len(unresolvedThreads) == 0 {
-		w.logger.Info("no unresolved copilot reviews found")
-		return nil
-	}
-
+func (w *Worker) fixReviews(ctx context.Context, wtDir string, threads []github.ReviewThread) error {
 	// Log details about unresolved threads for debugging
 	var threadDetails []string
-	for _, t := range w.cachedReviewThreads {
-		if t.IsResolved || t.IsOutdated {
-			continue
-		}
-		for _, c := range t.Comments {
-			if isCopilotAuthor(c.Author) {
-				commentPreview := c.Body
-				if len(commentPreview) > 100 {
-					commentPreview = commentPreview[:100] + "..."
-				}
-				threadDetails = append(threadDetails, fmt.Sprintf("%s:%d - %s", t.Path, t.Line, commentPreview))
-				break
-			}
+	for _, t := range threads {
+		commentPreview := t.Comments[0].Body
+		if len(commentPreview) > 100 {
+			commentPreview = commentPreview[:100] + "..."
 		}
+		threadDetails = append(threadDetails, fmt.Sprintf("%s:%d %s - %s", t.Path, t.Line, t.Comments[0].Author, commentPreview))
 	}
-	w.logger.Info("found unresolved copilot reviews", "count", len(unresolvedThreads), "threads", threadDetails)
+	w.logger.Info("found unresolved bot reviews", "count", len(threads), "threads", threadDetails)
 
-	if err := w.git.Fetch(ctx, wtDir); err != nil {
-		return fmt.Errorf("fetch: %w", err)
-	}
-
-	// Create output directory for Claude logs
-	outputDir := filepath.Join(wtDir, ".auto-claude-logs")
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("create output dir: %w", err)
-	}
-
-	w.onClaudeStart("fixing_reviews")
-	endCalled := false
-	defer func() {
-		if !endCalled {
-			w.onClaudeEnd()
-		}
-	}()
-
-	prURL := fmt.Sprintf("https://github.com/%s/%s/pull/%d", w.repo.Owner, w.repo.Name, w.pr.Number)
-	result, err := w.claude.RunCommandWithCallback(ctx, wtDir, outputDir, "fix-review-auto", w.onClaudeOutput, prURL)
-	w.onClaudeEnd()
-	endCalled = true
+	outcome, err := w.runReviewFix(ctx, wtDir, "fixing_reviews", "Review bots left the comments below", threads, nil)
 	if err != nil {
-		return fmt.Errorf("claude fix reviews: %w", err)
-	}
-	if !result.Success {
-		return fmt.Errorf("claude failed: %s", result.Output)
+		return err
 	}
 
-	// Parse summary to understand what was done
-	summary, parseErr := parseReviewFixSummary(result.Output)
-	if parseErr == nil {
-		w.logger.Info("review fix summary",
-			"total", summary.Total,
-			"applied", summary.Applied,
-			"questionable", summary.Questionable,
-			"invalid", summary.Invalid)
-	} else {
-		w.logger.Warn("failed to parse review fix summary",
-			"err", parseErr,
-			"output_file", result.OutputFile)
-	}
-
-	// Check if Claude actually created commits
-	hasChanges, err := w.git.HasUnpushedCommits(ctx, wtDir, w.pr.HeadRef)
-	if err != nil {
-		return fmt.Errorf("check unpushed commits: %w", err)
-	}
-
-	if !hasChanges {
-		if parseErr == nil && summary.Applied == 0 {
-			// Legitimate: all comments were skipped
-			w.logger.Info("no fixes applied - all comments skipped",
-				"total", summary.Total,
-				"questionable", summary.Questionable,
-				"invalid", summary.Invalid)
-
-			// Resolve threads even when no code changes applied
-			w.logger.Info("resolving copilot review threads", "count", len(unresolvedThreads))
-			for _, threadID := range unresolvedThreads {
-				if err := w.gh.ResolveReviewThread(ctx, threadID); err != nil {
-					w.logger.Error("failed to resolve thread", "thread_id", threadID, "err", err)
-					// Continue with other threads even if one fails
-				}
-			}
-
-			w.logger.Info("reviews marked as addressed with no code changes")
-			return nil // Success, not error
-		}
-		// Unexpected: skill should have created commits
-		if parseErr == nil {
-			w.logger.Error("no commits but fixes claimed",
-				"claimed_fixes", summary.Applied)
-			return fmt.Errorf("no commits created despite claiming %d fixes", summary.Applied)
-		}
-		// Parse failed, unclear what happened
-		return fmt.Errorf("no commits created by claude, cannot push")
-	}
-
-	if err := w.git.Push(ctx, wtDir, w.pr.HeadRef); err != nil {
-		return fmt.Errorf("push: %w", err)
-	}
-
-	// Auto-resolve all Copilot review threads after successful fix
-	w.logger.Info("resolving copilot review threads", "count", len(unresolvedThreads))
-	for _, threadID := range unresolvedThreads {
-		if err := w.gh.ResolveReviewThread(ctx, threadID); err != nil {
-			w.logger.Error("failed to resolve thread", "thread_id", threadID, "err", err)
-			// Continue with other threads even if one fails
-		}
-	}
-
-	w.logger.Info("reviews fixed, pushed, and resolved")
+	// Questionable threads stay open for a human
+	w.postReviewReplies(ctx, threads, nil, outcome, func(o string) bool {
+		return o == outcomeFixed || o == outcomeInvalid
+	})
+	w.logger.Info("bot reviews addressed")
 	return nil
 }
 
@@ -308,7 +158,7 @@ func (w *Worker) requestReview(ctx context.Context) error {
 	}
 
 	for _, comment := range comments {
-		if strings.Contains(comment, commentMarker) {
+		if strings.Contains(comment.Body, commentMarker) {
 			w.logger.Info("review request comment already posted, waiting for next poll")
 			return nil
 		}
@@ -324,15 +174,54 @@ func (w *Worker) requestReview(ctx context.Context) error {
 	return nil
 }
 
-func (w *Worker) merge(ctx context.Context) error {
-	err := w.gh.MergePR(ctx, w.repo.Owner, w.repo.Name, w.pr.Number, w.repo.MergeMethod)
-	if err != nil && strings.Contains(err.Error(), "Base branch was modified") {
+// merge hands a ready PR to GitHub according to the repo's merge strategy.
+func (w *Worker) merge(ctx context.Context, wtDir string) error {
+	if w.repo.MergeStrategy == "merge_queue" {
+		// The queue merges with the branch's own queue settings
+		if err := w.gh.EnqueuePR(ctx, w.repo.Owner, w.repo.Name, w.pr.Number); err != nil {
+			return err
+		}
+		w.logger.Info("PR added to merge queue")
+		return nil
+	}
+
+	opts, err := w.mergeOptions(ctx, wtDir)
+	if err != nil {
+		return fmt.Errorf("build commit message: %w", err)
+	}
+
+	if w.repo.MergeStrategy == "auto_merge" {
+		err := w.gh.EnableAutoMerge(ctx, w.repo.Owner, w.repo.Name, w.pr.Number, opts)
+		var clean *github.CleanStatusError
+		if !errors.As(err, &clean) {
+			if err == nil {
+				w.logger.Info("auto-merge enabled")
+			}
+			return err
+		}
+		w.logger.Info("PR already mergeable, merging directly")
+	}
+	return w.mergeDirect(ctx, opts)
+}
+
+func (w *Worker) mergeDirect(ctx context.Context, opts github.MergeOptions) error {
+	err := w.gh.MergePR(ctx, w.repo.Owner, w.repo.Name, w.pr.Number, opts)
+
+	var baseModified *github.BaseModifiedError
+	var conflict *github.MergeConflictError
+	switch {
+	case errors.As(err, &baseModified):
 		w.logger.Info("base branch modified, updating PR branch")
 		if updateErr := w.gh.UpdateBranch(ctx, w.repo.Owner, w.repo.Name, w.pr.Number); updateErr != nil {
 			return fmt.Errorf("update branch: %w", updateErr)
 		}
 		w.logger.Info("PR branch updated, will retry merge on next poll after checks pass")
 		return nil // Exit successfully, next poll will retry merge
+	case errors.As(err, &conflict):
+		w.logger.Info("PR has merge conflicts, they will be resolved on next poll", "err", err)
+		return nil
+	case err == nil:
+		w.logger.Info("PR merged successfully")
 	}
 	return err
 }
@@ -0,0 +1,98 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/marcin-skalski/auto-claude/internal/ci"
+	"github.com/marcin-skalski/auto-claude/internal/github"
+)
+
+const (
+	// ciLogDir holds trimmed job logs, relative to the worktree
+	ciLogDir = ".auto-claude-logs/ci"
+	// ciLogBudget caps each trimmed job log, in bytes
+	ciLogBudget = 16 << 10
+	// maxCILogs caps how many job logs are downloaded per fix attempt
+	maxCILogs = 5
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// describeFailingChecks lists the failing checks for the fix prompt. Logs of
+// failing Actions jobs are trimmed to the failure and written to ciLogDir, so
+// Claude can read them instead of fetching them itself.
+func (w *Worker) describeFailingChecks(ctx context.Context, wtDir string, failing []github.Check) string {
+	logDir := filepath.Join(wtDir, ciLogDir)
+	if err := ensureLogDir(logDir); err != nil {
+		w.logger.Warn("failed to create CI log dir, continuing without logs", "err", err)
+		logDir = ""
+	}
+
+	var b strings.Builder
+	downloaded := 0
+	for _, c := range failing {
+		fmt.Fprintf(&b, "- %s", c.Name)
+		if c.DetailsURL != "" {
+			fmt.Fprintf(&b, " (%s)", c.DetailsURL)
+		}
+
+		if logDir != "" && c.IsActionsJob() && downloaded < maxCILogs {
+			downloaded++
+			if file, err := w.writeJobLog(ctx, logDir, c); err != nil {
+				w.logger.Warn("failed to fetch CI job log", "check", c.Name, "job", c.ID, "err", err)
+			} else {
+				rel, _ := filepath.Rel(wtDir, file)
+				fmt.Fprintf(&b, ": failure excerpt of the job log in `%s`", rel)
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func (w *Worker) writeJobLog(ctx context.Context, logDir string, c github.Check) (string, error) {
+	log, err := w.jobLog(ctx, c)
+	if err != nil {
+		return "", err
+	}
+
+	name := strings.Trim(unsafeFileChars.ReplaceAllString(c.Name, "-"), "-")
+	file := filepath.Join(logDir, fmt.Sprintf("%d-%s.log", c.ID, name))
+	if err := os.WriteFile(file, []byte(ci.TrimLog(log, ciLogBudget)+"\n"), 0o644); err != nil {
+		return "", fmt.Errorf("write job log: %w", err)
+	}
+	return file, nil
+}
+
+// jobLog downloads an Actions job's log once per worker run; classification,
+// flaky detection and the fix prompt all read it.
+func (w *Worker) jobLog(ctx context.Context, c github.Check) (string, error) {
+	if log, ok := w.jobLogs[c.ID]; ok {
+		return log, nil
+	}
+	log, err := w.gh.GetJobLogs(ctx, w.repo.Owner, w.repo.Name, c.ID)
+	if err != nil {
+		return "", err
+	}
+	w.jobLogs[c.ID] = log
+	return log, nil
+}
+
+// ensureLogDir creates dir inside .auto-claude-logs, which ignores itself so
+// the logs never end up in Claude's commits.
+func ensureLogDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	root := filepath.Dir(dir)
+	ignore := filepath.Join(root, ".gitignore")
+	if _, err := os.Stat(ignore); err == nil {
+		return nil
+	}
+	return os.WriteFile(ignore, []byte("*\n"), 0o644)
+}
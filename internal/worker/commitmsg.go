@@ -0,0 +1,190 @@
+package worker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/marcin-skalski/auto-claude/internal/github"
+)
+
+// commitMessageData is what commit_message templates are executed with.
+type commitMessageData struct {
+	Title     string
+	Number    int
+	Body      string
+	Sections  map[string]string // PR body text under each markdown heading, key: heading text
+	Commits   []string          // commit subjects, oldest first
+	CoAuthors []string          // "Name <email>" of other commit authors and existing Co-authored-by trailers
+	Trailers  []string          // other trailers of the squashed commits, e.g. "Signed-off-by: ..."
+	Summary   string            // Claude's summary when commit_message.summarize is set
+}
+
+var (
+	headingRe = regexp.MustCompile(`^#{1,6}\s+(.+?)\s*#*$`)
+	trailerRe = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*):\s+\S`)
+	blankRuns = regexp.MustCompile(`\n{3,}`)
+)
+
+// mergeOptions builds the merge options for the PR, rendering the configured
+// commit message templates for squash merges.
+func (w *Worker) mergeOptions(ctx context.Context, wtDir string) (github.MergeOptions, error) {
+	opts := github.MergeOptions{Method: w.repo.MergeMethod}
+	cm := w.repo.CommitMessage
+	if cm == nil || w.repo.MergeMethod != "squash" {
+		return opts, nil
+	}
+
+	commits, err := w.gh.GetPRCommits(ctx, w.repo.Owner, w.repo.Name, w.pr.Number)
+	if err != nil {
+		return opts, fmt.Errorf("get commits: %w", err)
+	}
+	data := newCommitMessageData(w.pr, commits)
+	if cm.Summarize {
+		// Once per head: a merge that keeps failing mustn't pay for a run
+		// on every attempt
+		summary, ok := w.memory.commitSummary(w.pr.HeadSHA)
+		if !ok {
+			summary = w.summarizeCommits(ctx, wtDir, commits)
+			w.memory.setCommitSummary(w.pr.HeadSHA, summary)
+		}
+		data.Summary = summary
+	}
+
+	title, err := renderCommitTemplate("title", cm.Title, data)
+	if err != nil {
+		return opts, err
+	}
+	// A subject is one line; extra lines would spill into the body
+	title, _, _ = strings.Cut(title, "\n")
+	opts.CommitTitle = strings.TrimSpace(title)
+
+	if opts.CommitMessage, err = renderCommitTemplate("body", cm.Body, data); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+func renderCommitTemplate(name, text string, data commitMessageData) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parse commit %s template: %w", name, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("render commit %s template: %w", name, err)
+	}
+	return strings.TrimSpace(blankRuns.ReplaceAllString(b.String(), "\n\n")), nil
+}
+
+func newCommitMessageData(pr github.PRInfo, commits []github.Commit) commitMessageData {
+	data := commitMessageData{
+		Title:    pr.Title,
+		Number:   pr.Number,
+		Body:     strings.TrimSpace(pr.Body),
+		Sections: bodySections(pr.Body),
+	}
+
+	seenAuthors := make(map[string]bool)
+	seenTrailers := make(map[string]bool)
+	addCoAuthor := func(author string) {
+		if !seenAuthors[strings.ToLower(author)] {
+			seenAuthors[strings.ToLower(author)] = true
+			data.CoAuthors = append(data.CoAuthors, author)
+		}
+	}
+
+	for _, c := range commits {
+		subject, _, _ := strings.Cut(c.Message, "\n")
+		data.Commits = append(data.Commits, subject)
+
+		if c.AuthorLogin != pr.Author.Login && c.AuthorEmail != "" {
+			addCoAuthor(fmt.Sprintf("%s <%s>", c.AuthorName, c.AuthorEmail))
+		}
+		for _, t := range trailers(c.Message) {
+			key, value, _ := strings.Cut(t, ":")
+			if strings.EqualFold(key, "Co-authored-by") {
+				addCoAuthor(strings.TrimSpace(value))
+				continue
+			}
+			if !seenTrailers[t] {
+				seenTrailers[t] = true
+				data.Trailers = append(data.Trailers, t)
+			}
+		}
+	}
+	return data
+}
+
+// bodySections splits a markdown PR body by its headings.
+func bodySections(body string) map[string]string {
+	sections := make(map[string]string)
+	var heading string
+	var text strings.Builder
+	flush := func() {
+		if heading != "" {
+			sections[heading] = strings.TrimSpace(text.String())
+		}
+		text.Reset()
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if m := headingRe.FindStringSubmatch(line); m != nil {
+			flush()
+			heading = m[1]
+			continue
+		}
+		text.WriteString(line + "\n")
+	}
+	flush()
+	return sections
+}
+
+// trailers returns the "Key: value" lines of a commit message's final
+// paragraph, if every line of it is one.
+func trailers(message string) []string {
+	paragraphs := strings.Split(strings.TrimSpace(message), "\n\n")
+	if len(paragraphs) < 2 {
+		return nil
+	}
+	lines := strings.Split(paragraphs[len(paragraphs)-1], "\n")
+	for _, line := range lines {
+		if !trailerRe.MatchString(line) {
+			return nil
+		}
+	}
+	return lines
+}
+
+// summarizeCommits asks Claude for a short summary of the squashed commits.
+// Failures only cost the summary, not the merge.
+func (w *Worker) summarizeCommits(ctx context.Context, wtDir string, commits []github.Commit) string {
+	var log strings.Builder
+	for _, c := range commits {
+		fmt.Fprintf(&log, "commit %s\n%s\n\n", c.SHA, strings.TrimSpace(c.Message))
+	}
+
+	prompt := fmt.Sprintf(
+		"Summarize the changes of PR #%d (%q), which is about to be squash-merged, for its commit message body. Its commits are below. Reply with only the summary: plain text, at most 5 short bullet points starting with \"- \", lines wrapped at 72 characters, no headings. Do not modify any files.\n\n%s",
+		w.pr.Number, w.pr.Title, log.String(),
+	)
+
+	result, err := w.runClaude(ctx, wtDir, "summarizing_commits", prompt)
+	if err != nil {
+		w.logger.Warn("commit summary failed, merging without it", "err", err)
+		return ""
+	}
+	if !result.Success {
+		w.logger.Warn("commit summary failed, merging without it", "output", result.Output)
+		return ""
+	}
+	return strings.TrimSpace(result.Output)
+}
@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/marcin-skalski/auto-claude/internal/claude"
+	"github.com/marcin-skalski/auto-claude/internal/git"
+	"github.com/marcin-skalski/auto-claude/internal/github"
+)
+
+// GitHub is the GitHub API surface used by workers and the daemon.
+// *github.Client implements it; internal/fake provides an in-memory one.
+type GitHub interface {
+	ListOpenPRs(ctx context.Context, owner, repo string, limit int) ([]github.PRInfo, error)
+	GetPRDetail(ctx context.Context, owner, repo string, number int) (*github.PRInfo, error)
+	GetReviews(ctx context.Context, owner, repo string, number int) ([]github.Review, error)
+	RequestBotReviews(ctx context.Context, owner, repo string, number int, bots []string) error
+	AddThreadReply(ctx context.Context, threadID, body string) error
+	ListTeamMembers(ctx context.Context, org, team string) ([]string, error)
+	GetReviewThreads(ctx context.Context, owner, repo string, number int) ([]github.ReviewThread, error)
+	ResolveReviewThread(ctx context.Context, threadID string) error
+	UpdateBranch(ctx context.Context, owner, repo string, number int) error
+	RebaseBranch(ctx context.Context, owner, repo string, number int) error
+	PostComment(ctx context.Context, owner, repo string, number int, body string) error
+	GetComments(ctx context.Context, owner, repo string, number int) ([]github.Comment, error)
+	GetCommentsSince(ctx context.Context, owner, repo string, number int, since time.Time) ([]github.Comment, error)
+	UpdateComment(ctx context.Context, commentID, body string) error
+	AddReaction(ctx context.Context, subjectID, content string) error
+	GetLabel(ctx context.Context, owner, repo, name string) (*github.Label, error)
+	CreateLabel(ctx context.Context, owner, repo string, label github.Label) error
+	UpdateLabel(ctx context.Context, owner, repo string, label github.Label) error
+	AddLabels(ctx context.Context, owner, repo string, number int, names []string) error
+	RemoveLabel(ctx context.Context, owner, repo string, number int, name string) error
+	GetPermission(ctx context.Context, owner, repo, login string) (string, error)
+	GetRequiredChecks(ctx context.Context, owner, repo, branch string) (github.RequiredChecks, error)
+	RerunCheck(ctx context.Context, owner, repo string, check github.Check) error
+	CreateCheckRun(ctx context.Context, owner, repo string, run github.CheckRun) (int64, error)
+	UpdateCheckRun(ctx context.Context, owner, repo string, id int64, run github.CheckRun) error
+	SetCommitStatus(ctx context.Context, owner, repo, sha string, status github.CommitStatus) error
+	GetJobLogs(ctx context.Context, owner, repo string, jobID int64) (string, error)
+	GetCheckAnnotations(ctx context.Context, owner, repo string, checkID int64) ([]github.Annotation, error)
+	GetPRCommits(ctx context.Context, owner, repo string, number int) ([]github.Commit, error)
+	MergePR(ctx context.Context, owner, repo string, number int, opts github.MergeOptions) error
+	EnableAutoMerge(ctx context.Context, owner, repo string, number int, opts github.MergeOptions) error
+	EnqueuePR(ctx context.Context, owner, repo string, number int) error
+	RateLimit() github.RateLimit
+}
+
+// Agent runs the coding agent in a worktree. *claude.Client implements it.
+type Agent interface {
+	RunWithCallback(ctx context.Context, workdir, prompt string, callback claude.OutputCallback) (*claude.Result, error)
+}
+
+// Git manages clones, worktrees and pushes. *git.Client implements it.
+type Git interface {
+	EnsureClone(ctx context.Context, host, owner, repo string) error
+	AddWorktree(ctx context.Context, host, owner, repo, branch string, prNumber int) (string, error)
+	RemoveWorktree(ctx context.Context, host, owner, repo string, prNumber int) error
+	Fetch(ctx context.Context, dir string) error
+	Push(ctx context.Context, dir, branch string) error
+	HasUnpushedCommits(ctx context.Context, dir, branch string) (bool, error)
+	UnpushedCommits(ctx context.Context, dir, branch string) ([]string, error)
+}
+
+var (
+	_ GitHub = (*github.Client)(nil)
+	_ Agent  = (*claude.Client)(nil)
+	_ Git    = (*git.Client)(nil)
+)
@@ -0,0 +1,135 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/marcin-skalski/auto-claude/internal/ci"
+	"github.com/marcin-skalski/auto-claude/internal/config"
+	"github.com/marcin-skalski/auto-claude/internal/github"
+)
+
+// handleFailingChecks classifies the failing checks and acts per ci_failures.
+// Fixable failures get flaky re-runs first, then Claude; infrastructure
+// failures are re-run up to flaky.max_flaky_reruns times, then reported like
+// notify failures. Nothing is fixed or reported while re-runs are pending.
+func (w *Worker) handleFailingChecks(ctx context.Context, wtDir string) error {
+	var fix, rerun, notify []github.Check
+	categories := make(map[string]string) // key: check name
+	for _, c := range w.gatingChecks() {
+		if !c.Completed() || w.checkAction(c) != config.CheckFailing {
+			continue
+		}
+		category := w.classify(ctx, c)
+		categories[c.Name] = category
+		action := w.repo.FailureAction(category)
+		w.logger.Info("classified failing check", "check", c.Name, "category", category, "action", action)
+
+		switch action {
+		case config.FailureFix:
+			fix = append(fix, c)
+		case config.FailureRerun:
+			if c.ID != 0 && w.memory.Reruns(w.pr.HeadSHA, c.Name) < *w.repo.Flaky.MaxFlakyReruns {
+				rerun = append(rerun, c)
+			} else {
+				notify = append(notify, c)
+			}
+		case config.FailureNotify:
+			notify = append(notify, c)
+		}
+	}
+
+	for _, c := range rerun {
+		w.logger.Info("re-running check after infrastructure failure", "check", c.Name, "category", categories[c.Name])
+		if err := w.rerun(ctx, c); err != nil {
+			return err
+		}
+	}
+	rerunFlaky, err := w.rerunFlaky(ctx, fix)
+	if err != nil {
+		return err
+	}
+	if len(rerun) > 0 || rerunFlaky {
+		return nil
+	}
+
+	if len(notify) > 0 {
+		if err := w.notifyFailures(ctx, notify, categories); err != nil {
+			return err
+		}
+	}
+	if len(fix) > 0 {
+		return w.fixChecks(ctx, wtDir, fix)
+	}
+	return nil
+}
+
+// classify returns the failure category of a failing check, from its
+// annotations and job log. Results are remembered per check run.
+func (w *Worker) classify(ctx context.Context, c github.Check) string {
+	if category := w.memory.Category(w.pr.HeadSHA, c); category != "" {
+		return category
+	}
+
+	f := ci.Failure{Check: c.Name, TimedOut: c.Conclusion == github.ConclusionTimedOut}
+	complete := true
+	if c.ID != 0 {
+		annotations, err := w.gh.GetCheckAnnotations(ctx, w.repo.Owner, w.repo.Name, c.ID)
+		if err != nil {
+			w.logger.Warn("failed to get check annotations", "check", c.Name, "err", err)
+			complete = false
+		}
+		for _, a := range annotations {
+			f.Annotations = append(f.Annotations, a.Title+": "+a.Message)
+		}
+	}
+	if c.IsActionsJob() {
+		log, err := w.jobLog(ctx, c)
+		if err != nil {
+			w.logger.Warn("failed to fetch job log for classification", "check", c.Name, "err", err)
+			complete = false
+		}
+		f.Log = log
+	}
+
+	category := ci.Classify(f)
+	// Guesses from partial data are retried on the next poll
+	if complete {
+		w.memory.SetCategory(w.pr.HeadSHA, c, category)
+	}
+	return category
+}
+
+// notifyFailures comments once per head commit about failures auto-claude
+// leaves to humans.
+func (w *Worker) notifyFailures(ctx context.Context, checks []github.Check, categories map[string]string) error {
+	marker := fmt.Sprintf("<!-- auto-claude:ci-failures:%s -->", w.pr.HeadSHA)
+	comments, err := w.gh.GetComments(ctx, w.repo.Owner, w.repo.Name, w.pr.Number)
+	if err != nil {
+		return fmt.Errorf("get comments: %w", err)
+	}
+	for _, comment := range comments {
+		if strings.Contains(comment.Body, marker) {
+			w.logger.Info("failing checks already reported")
+			return nil
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("These checks are failing for reasons auto-claude doesn't fix automatically:\n\n")
+	for _, c := range checks {
+		fmt.Fprintf(&b, "- %s: `%s`", c.Name, categories[c.Name])
+		if c.DetailsURL != "" {
+			fmt.Fprintf(&b, " ([details](%s))", c.DetailsURL)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n" + marker)
+
+	if err := w.gh.PostComment(ctx, w.repo.Owner, w.repo.Name, w.pr.Number, b.String()); err != nil {
+		return fmt.Errorf("post comment: %w", err)
+	}
+	w.logger.Info("reported failing checks", "count", len(checks))
+	return nil
+}
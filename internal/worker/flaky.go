@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+
+	"github.com/marcin-skalski/auto-claude/internal/github"
+)
+
+// rerunFlaky re-runs failed Actions jobs that are still within their re-run
+// budget for the current head commit, so Claude only sees failures that
+// reproduce. It reports whether anything was re-run.
+func (w *Worker) rerunFlaky(ctx context.Context, failing []github.Check) (bool, error) {
+	rerun := false
+	for _, c := range failing {
+		if !c.IsActionsJob() {
+			continue
+		}
+		count := w.memory.Reruns(w.pr.HeadSHA, c.Name)
+		if count >= w.rerunLimit(ctx, c, count) {
+			continue
+		}
+
+		w.logger.Info("re-running failed job before fixing it", "check", c.Name, "attempt", count+1)
+		if err := w.rerun(ctx, c); err != nil {
+			return rerun, err
+		}
+		rerun = true
+	}
+	return rerun, nil
+}
+
+// rerunLimit is how often a failed job may be re-run on one head commit:
+// flaky.max_reruns, or flaky.max_flaky_reruns for jobs matching a known-flaky
+// check name or log signature. The log is only fetched once the plain limit
+// is used up.
+func (w *Worker) rerunLimit(ctx context.Context, c github.Check, count int) int {
+	flaky := w.repo.Flaky
+	limit := *flaky.MaxReruns
+	if count < limit || *flaky.MaxFlakyReruns <= limit {
+		return limit
+	}
+	if flaky.IsFlakyCheck(c.Name) {
+		return *flaky.MaxFlakyReruns
+	}
+	if len(flaky.KnownFlakyLogs) == 0 {
+		return limit
+	}
+	log, err := w.jobLog(ctx, c)
+	if err != nil {
+		w.logger.Warn("failed to fetch job log for flaky detection", "check", c.Name, "job", c.ID, "err", err)
+		return limit
+	}
+	if flaky.IsFlakyLog(log) {
+		w.logger.Info("job log matches a known-flaky pattern", "check", c.Name)
+		return *flaky.MaxFlakyReruns
+	}
+	return limit
+}
+
+// rerun re-runs a check and counts it against the head commit's budget.
+func (w *Worker) rerun(ctx context.Context, c github.Check) error {
+	if err := w.gh.RerunCheck(ctx, w.repo.Owner, w.repo.Name, c); err != nil {
+		return err
+	}
+	w.memory.AddRerun(w.pr.HeadSHA, c.Name)
+	return nil
+}
@@ -0,0 +1,83 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/marcin-skalski/auto-claude/internal/github"
+)
+
+// reviewReplyMarker tags the PR comment answering a review body.
+func reviewReplyMarker(reviewID string) string {
+	return fmt.Sprintf("<!-- auto-claude:review-reply:%s -->", reviewID)
+}
+
+// humanThreads returns the unresolved threads waiting for an answer to an
+// allowlisted reviewer, i.e. whose last comment is theirs.
+func (w *Worker) humanThreads() []github.ReviewThread {
+	var threads []github.ReviewThread
+	for _, t := range w.pr.ReviewThreads {
+		if t.IsResolved || t.IsOutdated || len(t.Comments) == 0 {
+			continue
+		}
+		if slices.Contains(w.reviewers, t.Comments[len(t.Comments)-1].Author) {
+			threads = append(threads, t)
+		}
+	}
+	return threads
+}
+
+// humanReviewBodies returns the allowlisted reviewers' review bodies that
+// weren't answered yet.
+func (w *Worker) humanReviewBodies(comments []github.Comment) []github.Review {
+	var reviews []github.Review
+	for _, r := range w.pr.Reviews {
+		if !slices.Contains(w.reviewers, r.Author) || strings.TrimSpace(r.Body) == "" {
+			continue
+		}
+		if r.State != "CHANGES_REQUESTED" && r.State != "COMMENTED" {
+			continue
+		}
+		marker := reviewReplyMarker(r.ID)
+		if slices.ContainsFunc(comments, func(c github.Comment) bool { return strings.Contains(c.Body, marker) }) {
+			continue
+		}
+		reviews = append(reviews, r)
+	}
+	return reviews
+}
+
+// addressHumanReviews has Claude address the allowlisted reviewers' open
+// threads and review bodies, then replies to each with its rationale. It
+// reports whether there was anything to address.
+func (w *Worker) addressHumanReviews(ctx context.Context, wtDir string) (bool, error) {
+	if len(w.reviewers) == 0 {
+		return false, nil
+	}
+	threads := w.humanThreads()
+	comments, err := w.gh.GetComments(ctx, w.repo.Owner, w.repo.Name, w.pr.Number)
+	if err != nil {
+		return false, fmt.Errorf("get comments: %w", err)
+	}
+	reviews := w.humanReviewBodies(comments)
+	if len(threads) == 0 && len(reviews) == 0 {
+		return false, nil
+	}
+
+	w.logger.Info("addressing reviewer feedback", "threads", len(threads), "reviews", len(reviews))
+
+	outcome, err := w.runReviewFix(ctx, wtDir, "addressing_reviews", "Reviewers left the feedback below", threads, reviews)
+	if err != nil {
+		return true, err
+	}
+
+	// Resolution is the reviewer's call unless resolve_threads is set
+	resolve := w.repo.HumanReviews != nil && w.repo.HumanReviews.ResolveThreads
+	w.postReviewReplies(ctx, threads, reviews, outcome, func(o string) bool {
+		return resolve && o != outcomeQuestionable
+	})
+	w.logger.Info("reviewer feedback addressed")
+	return true, nil
+}
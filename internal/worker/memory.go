@@ -0,0 +1,279 @@
+package worker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/marcin-skalski/auto-claude/internal/github"
+)
+
+// Memory is what a PR's workers remember across polls. Workers exit after
+// one action, so the daemon keeps one Memory per open PR and hands it to
+// each worker it starts for that PR. Re-runs, failure categories and the
+// status check are per head commit; a push starts over. A nil Memory
+// remembers nothing.
+type Memory struct {
+	mu         sync.Mutex
+	headSHA    string
+	reruns     map[string]int            // key: check name, re-runs on headSHA
+	categories map[string]classification // key: check name
+	checkRunID int64                     // status check run on headSHA
+	published  string                    // status last published on headSHA
+	summary    *string                   // Claude's commit summary of headSHA, "" if it failed
+	requests   map[string]bool           // key: Request* constant, for the next worker
+
+	fixState   string // state Claude last tried to fix, or ready
+	attempts   int    // Claude runs since fixState was entered
+	costUSD    float64
+	claudeRuns int
+	sessionID  string // of the latest Claude run
+	lastAction string
+	lastAt     time.Time
+
+	timeline *timeline // status comment as last written; nil: read it from the PR
+}
+
+type classification struct {
+	checkID  int64 // re-runs get a new ID and are classified again
+	category string
+}
+
+// NewMemory returns an empty Memory.
+func NewMemory() *Memory {
+	return &Memory{
+		reruns:     make(map[string]int),
+		categories: make(map[string]classification),
+		requests:   make(map[string]bool),
+	}
+}
+
+// Reset forgets re-runs, failure categories and fix attempts, so failures
+// are classified and re-run again, and a worker that gave up tries again.
+func (m *Memory) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reruns = make(map[string]int)
+	m.categories = make(map[string]classification)
+	m.attempts = 0
+}
+
+// enterState notes that the PR is in a state Claude fixes, or ready. Moving
+// to another one starts counting attempts over.
+func (m *Memory) enterState(state string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if state != m.fixState {
+		m.fixState = state
+		m.attempts = 0
+	}
+}
+
+// Attempts returns how often Claude tried to fix the PR's current state.
+func (m *Memory) Attempts() int {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.attempts
+}
+
+func (m *Memory) addAttempt() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attempts++
+}
+
+// addClaudeRun records the cost and session of a Claude run.
+func (m *Memory) addClaudeRun(costUSD float64, sessionID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.costUSD += costUSD
+	m.claudeRuns++
+	if sessionID != "" {
+		m.sessionID = sessionID
+	}
+}
+
+// setLastAction records the action a worker took last.
+func (m *Memory) setLastAction(action string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastAction = action
+	m.lastAt = time.Now()
+}
+
+// activity is what the status check shows besides the state.
+type activity struct {
+	lastAction string
+	lastAt     time.Time
+	costUSD    float64
+	claudeRuns int
+	sessionID  string
+}
+
+func (m *Memory) activity() activity {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return activity{
+		lastAction: m.lastAction,
+		lastAt:     m.lastAt,
+		costUSD:    m.costUSD,
+		claudeRuns: m.claudeRuns,
+		sessionID:  m.sessionID,
+	}
+}
+
+// statusCheckRun returns the status check run created on headSHA, 0 if none.
+func (m *Memory) statusCheckRun(headSHA string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if headSHA != m.headSHA {
+		return 0
+	}
+	return m.checkRunID
+}
+
+func (m *Memory) setStatusCheckRun(headSHA string, id int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setHead(headSHA)
+	m.checkRunID = id
+}
+
+// publish records status as published on headSHA. It reports false if it
+// already was.
+func (m *Memory) publish(headSHA, status string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setHead(headSHA)
+	if status == m.published {
+		return false
+	}
+	m.published = status
+	return true
+}
+
+// unpublish forgets the last published status, so it's published again.
+func (m *Memory) unpublish() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.published = ""
+}
+
+// statusTimeline returns the status comment's timeline, nil if it has to be
+// read from the PR.
+func (m *Memory) statusTimeline() *timeline {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.timeline == nil {
+		return nil
+	}
+	t := *m.timeline
+	t.Entries = append([]timelineEntry(nil), t.Entries...)
+	return &t
+}
+
+// setStatusTimeline records the timeline as written to the status comment.
+// nil forgets it, so the comment is read again.
+func (m *Memory) setStatusTimeline(t *timeline) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timeline = t
+}
+
+// commitSummary returns Claude's summary of the commits up to headSHA, and
+// whether they were summarized.
+func (m *Memory) commitSummary(headSHA string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if headSHA != m.headSHA || m.summary == nil {
+		return "", false
+	}
+	return *m.summary, true
+}
+
+func (m *Memory) setCommitSummary(headSHA, summary string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setHead(headSHA)
+	m.summary = &summary
+}
+
+// Request asks the PR's next worker to take an action, one of the Request*
+// constants, before evaluating the PR.
+func (m *Memory) Request(action string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[action] = true
+}
+
+// takeRequest reports whether action was requested, and clears the request.
+func (m *Memory) takeRequest(action string) bool {
+	if m == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	requested := m.requests[action]
+	delete(m.requests, action)
+	return requested
+}
+
+// Reruns returns how often check was re-run on the given head commit.
+func (m *Memory) Reruns(headSHA, check string) int {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if headSHA != m.headSHA {
+		return 0
+	}
+	return m.reruns[check]
+}
+
+// AddRerun records a re-run of check on the given head commit.
+func (m *Memory) AddRerun(headSHA, check string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setHead(headSHA)
+	m.reruns[check]++
+}
+
+// Category returns the failure category c was classified as, or "" if this
+// run of it wasn't classified.
+func (m *Memory) Category(headSHA string, c github.Check) string {
+	if m == nil {
+		return ""
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cl, ok := m.categories[c.Name]
+	if headSHA != m.headSHA || !ok || cl.checkID != c.ID {
+		return ""
+	}
+	return cl.category
+}
+
+// SetCategory records the failure category of c.
+func (m *Memory) SetCategory(headSHA string, c github.Check, category string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setHead(headSHA)
+	m.categories[c.Name] = classification{checkID: c.ID, category: category}
+}
+
+func (m *Memory) setHead(headSHA string) {
+	if headSHA != m.headSHA {
+		m.headSHA = headSHA
+		m.reruns = make(map[string]int)
+		m.categories = make(map[string]classification)
+		m.checkRunID = 0
+		m.published = ""
+		m.summary = nil
+	}
+}
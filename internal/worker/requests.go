@@ -0,0 +1,44 @@
+package worker
+
+import (
+	"context"
+
+	"github.com/marcin-skalski/auto-claude/internal/config"
+	"github.com/marcin-skalski/auto-claude/internal/github"
+)
+
+// Actions the daemon can request from a PR's next worker, see Memory.Request.
+const (
+	RequestFixChecks  = "fix-checks"  // have Claude fix every failing check, whatever its category
+	RequestFixReviews = "fix-reviews" // have Claude address every unresolved review bot thread
+)
+
+// runRequests takes the requested actions, if any. It reports whether it
+// did something, in which case the worker exits like after any action.
+func (w *Worker) runRequests(ctx context.Context, wtDir string) (bool, error) {
+	if w.memory.takeRequest(RequestFixChecks) {
+		if failing := w.failedChecks(); len(failing) > 0 {
+			return true, w.fixChecks(ctx, wtDir, failing)
+		}
+		w.logger.Info("fix of checks requested, but none are failing")
+	}
+	if w.memory.takeRequest(RequestFixReviews) {
+		if threads := w.botThreads(); len(threads) > 0 {
+			return true, w.fixReviews(ctx, wtDir, threads)
+		}
+		w.logger.Info("fix of reviews requested, but no bot threads are unresolved")
+	}
+	return false, nil
+}
+
+// failedChecks returns the completed gating checks that didn't pass, unless
+// their conclusion is configured as ignore. Failure categories don't matter.
+func (w *Worker) failedChecks() []github.Check {
+	var failed []github.Check
+	for _, c := range w.gatingChecks() {
+		if c.Completed() && w.repo.CheckAction(c.Conclusion) != config.CheckIgnore {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,147 @@
+package worker
+
+import (
+	"context"
+	"slices"
+
+	"github.com/marcin-skalski/auto-claude/internal/config"
+	"github.com/marcin-skalski/auto-claude/internal/github"
+)
+
+// ReviewStatus is where a review bot stands on a PR.
+type ReviewStatus int
+
+const (
+	ReviewMissing       ReviewStatus = iota // no review yet
+	ReviewUnresolved                        // unresolved threads to address
+	ReviewAwaitingHuman                     // unresolved threads auto-claude left for a human
+	ReviewDone
+)
+
+// BotReviewStatus returns the bot's review status on pr. Threads belong to
+// whoever started them. Unresolved threads come first: fixing them is a push that needs reviewing anyway.
+// Threads auto-claude already answered wait for a human, unless none is left
+// to address. Without open threads, a review of an earlier commit counts:
+// bots that don't re-review pushes, including auto-claude's own, would
+// otherwise hold the PR forever.
+func BotReviewStatus(bot config.ReviewBot, pr github.PRInfo) ReviewStatus {
+	awaitingHuman := false
+	for _, t := range pr.ReviewThreads {
+		if t.IsResolved || t.IsOutdated || len(t.Comments) == 0 || !bot.IsLogin(t.Comments[0].Author) {
+			continue
+		}
+		if !awaitsHuman(t) {
+			return ReviewUnresolved
+		}
+		awaitingHuman = true
+	}
+	if awaitingHuman {
+		return ReviewAwaitingHuman
+	}
+
+	// Bots typically leave COMMENTED reviews, not approvals, so approval is
+	// enforced via reviewDecision
+	if _, ok := github.LatestReview(pr.Reviews, bot.IsLogin); !ok {
+		return ReviewMissing
+	}
+	return ReviewDone
+}
+
+// evaluateBotReviews returns the state for required bot reviews that are
+// missing or have unresolved threads, if any, including threads left for a
+// human.
+func (w *Worker) evaluateBotReviews() (state, bool) {
+	if config.IsRenovateLogin(w.pr.Author.Login) {
+		return 0, false
+	}
+	var missing, awaitingHuman []string
+	for _, bot := range w.repo.ReviewBots {
+		if !bot.Required {
+			continue
+		}
+		switch BotReviewStatus(bot, w.pr) {
+		case ReviewUnresolved:
+			return stateReviewsPending, true
+		case ReviewAwaitingHuman:
+			awaitingHuman = append(awaitingHuman, bot.Name)
+		case ReviewMissing:
+			missing = append(missing, bot.Name)
+		}
+	}
+	if len(awaitingHuman) > 0 {
+		w.logger.Info("bot review threads wait for a human", "bots", awaitingHuman)
+		return stateAwaitingHuman, true
+	}
+	if len(missing) > 0 {
+		w.logger.Info("waiting for bot reviews", "bots", missing)
+		return stateBotReviewPending, true
+	}
+	return 0, false
+}
+
+// autoFixThreads returns the unresolved review threads started by bots with
+// auto_fix, except those auto-claude already replied to last.
+func (w *Worker) autoFixThreads() []github.ReviewThread {
+	var threads []github.ReviewThread
+	for _, t := range w.pr.ReviewThreads {
+		if t.IsResolved || t.IsOutdated || len(t.Comments) == 0 {
+			continue
+		}
+		if awaitsHuman(t) {
+			continue
+		}
+		if bot, ok := w.reviewBot(t.Comments[0].Author); ok && bot.AutoFix {
+			threads = append(threads, t)
+		}
+	}
+	return threads
+}
+
+// botThreads returns all unresolved review threads started by review bots,
+// whether or not they auto-fix.
+func (w *Worker) botThreads() []github.ReviewThread {
+	var threads []github.ReviewThread
+	for _, t := range w.pr.ReviewThreads {
+		if t.IsResolved || t.IsOutdated || len(t.Comments) == 0 {
+			continue
+		}
+		if _, ok := w.reviewBot(t.Comments[0].Author); ok {
+			threads = append(threads, t)
+		}
+	}
+	return threads
+}
+
+func (w *Worker) reviewBot(login string) (config.ReviewBot, bool) {
+	for _, bot := range w.repo.ReviewBots {
+		if bot.IsLogin(login) {
+			return bot, true
+		}
+	}
+	return config.ReviewBot{}, false
+}
+
+// requestBotReviews asks the review bots that can be requested to review the
+// head commit, skipping those that already did or have a pending request.
+func (w *Worker) requestBotReviews(ctx context.Context) error {
+	var logins []string
+	for _, bot := range w.repo.ReviewBots {
+		if bot.Request == "" || slices.ContainsFunc(w.pr.RequestedReviews, bot.IsLogin) {
+			continue
+		}
+		if latest, ok := github.LatestReview(w.pr.Reviews, bot.IsLogin); ok && latest.CommitSHA == w.pr.HeadSHA {
+			continue
+		}
+		logins = append(logins, bot.Request)
+	}
+
+	if len(logins) == 0 {
+		w.logger.Info("bot reviews requested, waiting for next poll")
+		return nil
+	}
+	if err := w.gh.RequestBotReviews(ctx, w.repo.Owner, w.repo.Name, w.pr.Number, logins); err != nil {
+		return err
+	}
+	w.logger.Info("requested bot reviews of head commit", "bots", logins, "head", w.pr.HeadSHA)
+	return nil
+}
@@ -0,0 +1,207 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/marcin-skalski/auto-claude/internal/github"
+)
+
+// replyMarker tags auto-claude's thread replies. A thread whose last comment
+// is one waits for a human.
+const replyMarker = "<!-- auto-claude:reply -->"
+
+// awaitsHuman reports whether auto-claude answered t last, leaving it open
+// for a human, e.g. because Claude found the feedback questionable.
+func awaitsHuman(t github.ReviewThread) bool {
+	return len(t.Comments) > 0 && strings.Contains(t.Comments[len(t.Comments)-1].Body, replyMarker)
+}
+
+// Per-item outcomes Claude reports after addressing review feedback
+const (
+	outcomeFixed        = "fixed"        // valid; the code was changed
+	outcomeQuestionable = "questionable" // unclear; left for a human
+	outcomeInvalid      = "invalid"      // wrong or not applicable; nothing changed
+)
+
+var jsonBlockRe = regexp.MustCompile("(?s)```json\\s*\\n(.*?)\\n\\s*```")
+
+// reviewOutcome is the JSON Claude ends its reply with after addressing
+// review feedback.
+type reviewOutcome struct {
+	Threads []itemOutcome `json:"threads"`
+	Reviews []itemOutcome `json:"reviews"`
+}
+
+// itemOutcome is Claude's verdict on one thread or review body, keyed by its
+// GraphQL ID.
+type itemOutcome struct {
+	ID        string `json:"id"`
+	Outcome   string `json:"outcome"`
+	Rationale string `json:"rationale"`
+}
+
+// runReviewFix has Claude address review threads and review bodies, pushes
+// its commits and returns its per-item outcome. intro says who left the
+// feedback; action names the Claude run. If the outcome can't be read but
+// commits were pushed, every item is questionable, with a reply pointing to
+// the commits.
+func (w *Worker) runReviewFix(ctx context.Context, wtDir, action, intro string, threads []github.ReviewThread, reviews []github.Review) (reviewOutcome, error) {
+	if err := w.git.Fetch(ctx, wtDir); err != nil {
+		return reviewOutcome{}, fmt.Errorf("fetch: %w", err)
+	}
+
+	prompt := fmt.Sprintf(
+		"%s on PR #%d. Address each item: where the feedback is valid, change the code and commit with -s -S flags. Before committing, run these checks and confirm each passes: `golangci-lint run`, `go test ./...`, `go build ./cmd/auto-claude/`. Do not push.\n\n"+
+			"End your reply with a ```json block holding one entry per item. outcome is %q (valid, you changed the code), %q (unclear or a judgment call; change nothing and leave it for a human) or %q (wrong or not applicable; change nothing). rationale is a short reply to the reviewer: what you changed, or why not. Format:\n"+
+			"{\"threads\": [{\"id\": \"<thread id>\", \"outcome\": \"...\", \"rationale\": \"...\"}], \"reviews\": [{\"id\": \"<review id>\", \"outcome\": \"...\", \"rationale\": \"...\"}]}\n\n%s",
+		intro, w.pr.Number, outcomeFixed, outcomeQuestionable, outcomeInvalid, describeFeedback(threads, reviews),
+	)
+
+	result, err := w.runClaude(ctx, wtDir, action, prompt)
+	if err != nil {
+		return reviewOutcome{}, fmt.Errorf("claude %s: %w", strings.ReplaceAll(action, "_", " "), err)
+	}
+	if !result.Success {
+		return reviewOutcome{}, fmt.Errorf("claude failed: %s", result.Output)
+	}
+
+	outcome, parseErr := parseReviewOutcome(result.Output)
+	if parseErr == nil {
+		w.logReviewOutcome(outcome)
+	}
+
+	hasChanges, err := w.git.HasUnpushedCommits(ctx, wtDir, w.pr.HeadRef)
+	if err != nil {
+		return reviewOutcome{}, fmt.Errorf("check unpushed commits: %w", err)
+	}
+	if !hasChanges && parseErr == nil && outcome.count(outcomeFixed) > 0 {
+		return reviewOutcome{}, fmt.Errorf("no commits created despite claiming %d fixes", outcome.count(outcomeFixed))
+	}
+	if !hasChanges && parseErr != nil {
+		return reviewOutcome{}, parseErr
+	}
+	// Push first, so replies describe code the reviewer can see
+	if hasChanges {
+		if err := w.push(ctx, wtDir); err != nil {
+			return reviewOutcome{}, fmt.Errorf("push: %w", err)
+		}
+	}
+	if parseErr != nil {
+		w.logger.Warn("failed to read review fix outcome, replying with the pushed commits", "err", parseErr)
+		return pushedOutcome(threads, reviews, w.pushed), nil
+	}
+	return outcome, nil
+}
+
+// pushedOutcome is the outcome of a review fix whose per-item outcome
+// couldn't be read: each item is left for a human, pointing to the commits.
+func pushedOutcome(threads []github.ReviewThread, reviews []github.Review, commits []string) reviewOutcome {
+	var shas []string
+	for _, sha := range commits {
+		shas = append(shas, sha[:min(len(sha), 7)])
+	}
+	rationale := fmt.Sprintf("Claude pushed %s for the review feedback but didn't report what it did about this item. Please check whether the commits address it.", strings.Join(shas, ", "))
+
+	var outcome reviewOutcome
+	for _, t := range threads {
+		outcome.Threads = append(outcome.Threads, itemOutcome{ID: t.ID, Outcome: outcomeQuestionable, Rationale: rationale})
+	}
+	for _, r := range reviews {
+		outcome.Reviews = append(outcome.Reviews, itemOutcome{ID: r.ID, Outcome: outcomeQuestionable, Rationale: rationale})
+	}
+	return outcome
+}
+
+func (w *Worker) logReviewOutcome(outcome reviewOutcome) {
+	w.logger.Info("review fix outcome",
+		"fixed", outcome.count(outcomeFixed),
+		"questionable", outcome.count(outcomeQuestionable),
+		"invalid", outcome.count(outcomeInvalid))
+}
+
+func (o reviewOutcome) count(outcome string) int {
+	n := 0
+	for _, item := range append(o.Threads, o.Reviews...) {
+		if item.Outcome == outcome {
+			n++
+		}
+	}
+	return n
+}
+
+// postReviewReplies posts Claude's rationale on each thread, and as a PR
+// comment for each review body, then resolves the threads whose outcome
+// resolve accepts. Failures are logged; the item is addressed again later.
+func (w *Worker) postReviewReplies(ctx context.Context, threads []github.ReviewThread, reviews []github.Review, outcome reviewOutcome, resolve func(outcome string) bool) {
+	items := make(map[string]itemOutcome, len(outcome.Threads)+len(outcome.Reviews))
+	for _, item := range append(outcome.Threads, outcome.Reviews...) {
+		item.Rationale = strings.TrimSpace(item.Rationale)
+		items[item.ID] = item
+	}
+
+	for _, t := range threads {
+		item, ok := items[t.ID]
+		if !ok || item.Rationale == "" {
+			w.logger.Warn("no outcome for review thread", "thread_id", t.ID, "path", t.Path)
+			continue
+		}
+		body := fmt.Sprintf("**%s**: %s\n\n%s", item.Outcome, item.Rationale, replyMarker)
+		if err := w.gh.AddThreadReply(ctx, t.ID, body); err != nil {
+			w.logger.Error("failed to reply to thread", "thread_id", t.ID, "err", err)
+			continue
+		}
+		if !resolve(item.Outcome) {
+			continue
+		}
+		if err := w.gh.ResolveReviewThread(ctx, t.ID); err != nil {
+			w.logger.Error("failed to resolve thread", "thread_id", t.ID, "err", err)
+		}
+	}
+
+	for _, r := range reviews {
+		item, ok := items[r.ID]
+		if !ok || item.Rationale == "" {
+			w.logger.Warn("no outcome for review", "review_id", r.ID, "author", r.Author)
+			continue
+		}
+		quote, _, _ := strings.Cut(strings.TrimSpace(r.Body), "\n")
+		body := fmt.Sprintf("> %s\n\n@%s **%s**: %s\n\n%s", quote, r.Author, item.Outcome, item.Rationale, reviewReplyMarker(r.ID))
+		if err := w.gh.PostComment(ctx, w.repo.Owner, w.repo.Name, w.pr.Number, body); err != nil {
+			w.logger.Error("failed to reply to review", "review_id", r.ID, "err", err)
+		}
+	}
+}
+
+func describeFeedback(threads []github.ReviewThread, reviews []github.Review) string {
+	var b strings.Builder
+	for _, t := range threads {
+		fmt.Fprintf(&b, "### Thread %s on %s:%d\n", t.ID, t.Path, t.Line)
+		for _, c := range t.Comments {
+			body := strings.TrimSpace(strings.ReplaceAll(c.Body, replyMarker, ""))
+			fmt.Fprintf(&b, "**%s**: %s\n", c.Author, body)
+		}
+		b.WriteString("\n")
+	}
+	for _, r := range reviews {
+		fmt.Fprintf(&b, "### Review %s by %s (%s)\n%s\n\n", r.ID, r.Author, r.State, strings.TrimSpace(r.Body))
+	}
+	return b.String()
+}
+
+// parseReviewOutcome reads the last ```json block of Claude's reply, or the
+// whole reply if it has none.
+func parseReviewOutcome(output string) (reviewOutcome, error) {
+	text := strings.TrimSpace(output)
+	if matches := jsonBlockRe.FindAllStringSubmatch(output, -1); len(matches) > 0 {
+		text = matches[len(matches)-1][1]
+	}
+	var outcome reviewOutcome
+	if err := json.Unmarshal([]byte(text), &outcome); err != nil {
+		return outcome, fmt.Errorf("parse review outcome: %w", err)
+	}
+	return outcome, nil
+}
@@ -0,0 +1,204 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/marcin-skalski/auto-claude/internal/claude"
+	"github.com/marcin-skalski/auto-claude/internal/config"
+	"github.com/marcin-skalski/auto-claude/internal/github"
+)
+
+// runClaude runs Claude in the worktree under the given action name, and
+// remembers its cost and session for the status check.
+func (w *Worker) runClaude(ctx context.Context, wtDir, action, prompt string) (*claude.Result, error) {
+	w.onClaudeStart(action)
+	defer w.onClaudeEnd()
+	result, err := w.claude.RunWithCallback(ctx, wtDir, prompt, w.onClaudeOutput)
+	w.claudeAction = action
+	if result != nil {
+		w.memory.addClaudeRun(result.TotalCostUSD, result.SessionID)
+		w.actionCost += result.TotalCostUSD
+	}
+	return result, err
+}
+
+// isFixState reports whether Claude works on the PR in state s.
+// max_fix_attempts counts Claude runs in one of these.
+func isFixState(s state) bool {
+	return s == stateConflicting || s == stateChecksFailing || s == stateReviewsPending
+}
+
+// outOfAttempts reports whether Claude already tried max_fix_attempts times
+// to get the PR out of state s.
+func (w *Worker) outOfAttempts(s state) bool {
+	limit := *w.repo.MaxFixAttempts
+	return isFixState(s) && limit > 0 && w.memory.Attempts() >= limit
+}
+
+// giveUpReason says why auto-claude stopped working on the PR in state s,
+// until a human steps in. Empty if it didn't.
+func (w *Worker) giveUpReason(s state) string {
+	switch {
+	case w.outOfAttempts(s):
+		return fmt.Sprintf("Claude tried %d times without getting the PR out of `%s`. Comment `/auto-claude retry` to try again.", w.memory.Attempts(), stateString(s))
+	case s == stateChecksBlocked:
+		return "Checks need attention from a human."
+	case s == stateDequeued:
+		return "The PR was removed from the merge queue; waiting for a new push."
+	default:
+		return ""
+	}
+}
+
+// actionName names the action taken in state s when Claude didn't run.
+func actionName(s state) string {
+	switch s {
+	case stateChecksFailing:
+		return "handling_failing_checks"
+	case stateChecksRerun:
+		return "rerunning_checks"
+	case stateBotReviewPending:
+		return "requesting_bot_reviews"
+	case stateReviewsPending:
+		return "requesting_review"
+	case stateAutoMergePending:
+		return "updating_branch"
+	default:
+		return stateString(s)
+	}
+}
+
+// recordAction remembers the action just taken for the status check: the
+// Claude run, if there was one, or name.
+func (w *Worker) recordAction(name string, err error) {
+	if w.claudeAction != "" {
+		name = w.claudeAction
+	}
+	if err != nil {
+		name += " (failed)"
+	}
+	w.memory.setLastAction(name)
+}
+
+// publishStatus shows the evaluated state, the last action and the Claude
+// cost so far on the head commit, as configured by status_check. It's in
+// progress while auto-claude works, succeeds once the PR is ready to merge,
+// and concludes with give_up_conclusion once auto-claude gave up. A check
+// branch protection requires is neutral instead of in progress, since it
+// would block the merge it's waiting for. Failures are only logged.
+func (w *Worker) publishStatus(ctx context.Context, s state) {
+	sc := w.repo.StatusCheck
+	if sc == nil || w.pr.HeadSHA == "" {
+		return
+	}
+
+	run := github.CheckRun{
+		Name:    sc.Name,
+		HeadSHA: w.pr.HeadSHA,
+		Status:  "in_progress",
+		Title:   stateString(s),
+	}
+	reason := w.giveUpReason(s)
+	switch {
+	case reason != "":
+		run.Status, run.Conclusion, run.Title = "completed", sc.GiveUpConclusion, "Gave up: "+stateString(s)
+	case s == stateReady, s == stateAutoMergePending, s == stateMergeQueued:
+		run.Status, run.Conclusion, run.Title = "completed", "success", "Ready to merge"
+	case slices.Contains(w.required.Names, sc.Name):
+		run.Status, run.Conclusion = "completed", "neutral"
+	}
+
+	a := w.memory.activity()
+	if sc.TranscriptURL != "" && a.sessionID != "" {
+		run.DetailsURL = strings.ReplaceAll(sc.TranscriptURL, "{session_id}", a.sessionID)
+	}
+	run.Summary = statusSummary(s, reason, a, run.DetailsURL)
+
+	if !w.memory.publish(w.pr.HeadSHA, sc.Kind+"\n"+run.Status+"\n"+run.Conclusion+"\n"+run.Title+"\n"+run.Summary) {
+		return
+	}
+	var err error
+	if sc.Kind == config.StatusCheckRun {
+		err = w.publishCheckRun(ctx, run)
+	} else {
+		err = w.gh.SetCommitStatus(ctx, w.repo.Owner, w.repo.Name, w.pr.HeadSHA, commitStatus(run, a))
+	}
+	if err != nil {
+		w.memory.unpublish()
+		w.logger.Warn("failed to publish status check", "err", err)
+	}
+}
+
+func (w *Worker) publishCheckRun(ctx context.Context, run github.CheckRun) error {
+	id := w.memory.statusCheckRun(run.HeadSHA)
+	if id == 0 {
+		// Created by an earlier daemon process
+		for _, c := range w.pr.Checks {
+			if c.Name == run.Name && c.ID != 0 {
+				id = c.ID
+			}
+		}
+	}
+	if id != 0 {
+		if err := w.gh.UpdateCheckRun(ctx, w.repo.Owner, w.repo.Name, id, run); err != nil {
+			return err
+		}
+		w.memory.setStatusCheckRun(run.HeadSHA, id)
+		return nil
+	}
+	id, err := w.gh.CreateCheckRun(ctx, w.repo.Owner, w.repo.Name, run)
+	if err != nil {
+		return err
+	}
+	w.memory.setStatusCheckRun(run.HeadSHA, id)
+	return nil
+}
+
+func statusSummary(s state, reason string, a activity, transcriptURL string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**State:** `%s`\n\n", stateString(s))
+	if reason != "" {
+		fmt.Fprintf(&b, "**Gave up:** %s\n\n", reason)
+	}
+	if a.lastAction != "" {
+		fmt.Fprintf(&b, "**Last action:** `%s` at %s\n\n", a.lastAction, a.lastAt.UTC().Format("2006-01-02 15:04 UTC"))
+	} else {
+		b.WriteString("**Last action:** none yet\n\n")
+	}
+	fmt.Fprintf(&b, "**Claude cost:** $%.2f over %d runs\n", a.costUSD, a.claudeRuns)
+	switch {
+	case transcriptURL != "":
+		fmt.Fprintf(&b, "\n**Transcript:** [session %s](%s)\n", a.sessionID, transcriptURL)
+	case a.sessionID != "":
+		fmt.Fprintf(&b, "\n**Claude session:** `%s`\n", a.sessionID)
+	}
+	return b.String()
+}
+
+// commitStatus condenses a check run into a commit status. Statuses have no
+// neutral state, so a neutral conclusion is a success.
+func commitStatus(run github.CheckRun, a activity) github.CommitStatus {
+	state := "pending"
+	switch run.Conclusion {
+	case "success", "neutral":
+		state = "success"
+	case "failure":
+		state = "failure"
+	}
+	description := run.Title
+	if a.lastAction != "" {
+		description += " · last: " + a.lastAction
+	}
+	if a.claudeRuns > 0 {
+		description += fmt.Sprintf(" · $%.2f", a.costUSD)
+	}
+	return github.CommitStatus{
+		Context:     run.Name,
+		State:       state,
+		Description: description,
+		TargetURL:   run.DetailsURL,
+	}
+}
@@ -0,0 +1,232 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// statusCommentMarker tags the PR comment auto-claude keeps up to date with
+// its timeline, see config.StatusCommentConfig.
+const statusCommentMarker = "<!-- auto-claude:status -->"
+
+// maxCommentLength is GitHub's limit on a comment body, in characters.
+const maxCommentLength = 65536
+
+// timelineRe matches the timeline data hidden in the status comment. JSON
+// escapes '>', so the data can't end the HTML comment early.
+var timelineRe = regexp.MustCompile(`<!-- auto-claude:timeline (.*?) -->`)
+
+// timeline is the status comment's content. It's stored in the comment
+// itself, so it survives daemon restarts.
+type timeline struct {
+	CommentID    string          `json:"-"` // empty until the comment is posted and read back
+	TotalCostUSD float64         `json:"total_cost_usd"`
+	Entries      []timelineEntry `json:"entries"`
+}
+
+// timelineEntry is an action taken in a state, or a wait in it.
+type timelineEntry struct {
+	At       time.Time     `json:"at"`
+	State    string        `json:"state"`
+	Action   string        `json:"action,omitempty"`
+	Commits  []string      `json:"commits,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	CostUSD  float64       `json:"cost_usd,omitempty"`
+	Waiting  string        `json:"waiting,omitempty"` // reason, when no action was taken
+	Error    string        `json:"error,omitempty"`
+}
+
+// repeats reports whether e only repeats the previous entry, like a wait
+// noted again on the next poll. Claude runs and pushes always count.
+func (e timelineEntry) repeats(prev timelineEntry) bool {
+	if e.CostUSD > 0 || len(e.Commits) > 0 || prev.CostUSD > 0 || len(prev.Commits) > 0 {
+		return false
+	}
+	return e.State == prev.State && e.Action == prev.Action && e.Waiting == prev.Waiting && e.Error == prev.Error
+}
+
+// waitReason says what auto-claude waits for in state s, in which it takes
+// no action. Empty if it acts.
+func (w *Worker) waitReason(s state) string {
+	if reason := w.giveUpReason(s); reason != "" {
+		return "Gave up: " + reason
+	}
+	switch s {
+	case stateDraft:
+		return "The PR is a draft."
+	case stateChecksPending:
+		return "Checks are running, or GitHub is still computing mergeability."
+	case stateMergeQueued:
+		return "The PR is in the merge queue."
+	case stateAwaitingHuman:
+		return "Review bot threads Claude left open wait for a human to answer or resolve them."
+	case stateAutoMergePending:
+		if w.pr.MergeStateStatus != "BEHIND" {
+			return "Auto-merge is enabled; waiting for GitHub to merge."
+		}
+	}
+	return ""
+}
+
+// noteAction adds the action just taken in state s to the status comment:
+// the Claude run, if there was one, or name.
+func (w *Worker) noteAction(ctx context.Context, s, name string, started time.Time, err error) {
+	if w.claudeAction != "" {
+		name = w.claudeAction
+	}
+	e := timelineEntry{
+		State:    s,
+		Action:   name,
+		Commits:  w.pushed,
+		Duration: time.Since(started),
+		CostUSD:  w.actionCost,
+	}
+	if err != nil {
+		e.Error = errorSummary(err.Error())
+	}
+	w.updateStatusComment(ctx, e)
+}
+
+// noteWait adds a wait in state s to the status comment.
+func (w *Worker) noteWait(ctx context.Context, s state, reason string) {
+	w.updateStatusComment(ctx, timelineEntry{State: stateString(s), Waiting: reason})
+}
+
+// updateStatusComment appends e to the PR's status comment, posting the
+// comment if there's none yet. Failures are only logged.
+func (w *Worker) updateStatusComment(ctx context.Context, e timelineEntry) {
+	sc := w.repo.StatusComment
+	if sc == nil || !sc.Enabled {
+		return
+	}
+
+	t := w.memory.statusTimeline()
+	if t == nil {
+		var err error
+		if t, err = w.readStatusComment(ctx); err != nil {
+			w.logger.Warn("failed to read status comment", "err", err)
+			return
+		}
+		w.memory.setStatusTimeline(t)
+	}
+	if n := len(t.Entries); n > 0 && e.repeats(t.Entries[n-1]) {
+		return
+	}
+
+	e.At = time.Now().UTC()
+	t.TotalCostUSD += e.CostUSD
+	t.Entries = append(t.Entries, e)
+	if n := len(t.Entries); n > sc.MaxEntries {
+		t.Entries = t.Entries[n-sc.MaxEntries:]
+	}
+
+	body := renderStatusComment(t)
+	for len(body) > maxCommentLength && len(t.Entries) > 1 {
+		t.Entries = t.Entries[1:]
+		body = renderStatusComment(t)
+	}
+	var err error
+	if t.CommentID != "" {
+		err = w.gh.UpdateComment(ctx, t.CommentID, body)
+	} else {
+		err = w.gh.PostComment(ctx, w.repo.Owner, w.repo.Name, w.pr.Number, body)
+	}
+	if err != nil || t.CommentID == "" {
+		// Read the comment again next time: its ID is only known once
+		// posted, and a failed edit may mean it was deleted
+		w.memory.setStatusTimeline(nil)
+	} else {
+		w.memory.setStatusTimeline(t)
+	}
+	if err != nil {
+		w.logger.Warn("failed to write status comment", "err", err)
+	}
+}
+
+// readStatusComment returns the timeline of the PR's status comment, or an
+// empty one without a comment ID if there's none.
+func (w *Worker) readStatusComment(ctx context.Context) (*timeline, error) {
+	comments, err := w.gh.GetComments(ctx, w.repo.Owner, w.repo.Name, w.pr.Number)
+	if err != nil {
+		return nil, fmt.Errorf("get comments: %w", err)
+	}
+	t := &timeline{}
+	for _, c := range slices.Backward(comments) {
+		// Quotes of the comment by others can't be edited
+		if !c.ByViewer || !strings.Contains(c.Body, statusCommentMarker) {
+			continue
+		}
+		if m := timelineRe.FindStringSubmatch(c.Body); m != nil {
+			if err := json.Unmarshal([]byte(m[1]), t); err != nil {
+				// Start over in the same comment
+				w.logger.Warn("failed to parse status comment timeline", "err", err)
+				t = &timeline{}
+			}
+		}
+		// Entries written before errors were shortened
+		for i := range t.Entries {
+			t.Entries[i].Error = errorSummary(t.Entries[i].Error)
+		}
+		t.CommentID = c.ID
+		break
+	}
+	return t, nil
+}
+
+// errorSummary shortens an error for the public timeline to its first line
+// and at most 200 bytes. Failed Claude runs carry their whole output.
+func errorSummary(s string) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	if len(s) > 200 {
+		s = strings.ToValidUTF8(s[:200], "") + "..."
+	}
+	return s
+}
+
+func renderStatusComment(t *timeline) string {
+	var b strings.Builder
+	b.WriteString("### auto-claude status\n\n")
+	if n := len(t.Entries); n > 0 {
+		fmt.Fprintf(&b, "**State:** `%s` · **Claude cost:** $%.2f\n\n", t.Entries[n-1].State, t.TotalCostUSD)
+	}
+	b.WriteString("| Time (UTC) | State | Event | Duration | Cost | Commits |\n")
+	b.WriteString("|---|---|---|---|---|---|\n")
+	for _, e := range t.Entries {
+		event := "Waiting: " + e.Waiting
+		if e.Action != "" {
+			event = "`" + e.Action + "`"
+			if e.Error != "" {
+				event += " failed: " + e.Error
+			}
+		}
+		var duration, cost string
+		if e.Duration > 0 {
+			duration = e.Duration.Round(time.Second).String()
+		}
+		if e.CostUSD > 0 {
+			cost = fmt.Sprintf("$%.2f", e.CostUSD)
+		}
+		var commits []string
+		for _, sha := range e.Commits {
+			commits = append(commits, sha[:min(len(sha), 7)])
+		}
+		fmt.Fprintf(&b, "| %s | `%s` | %s | %s | %s | %s |\n",
+			e.At.Format("2006-01-02 15:04"), e.State, tableCell(event), duration, cost, strings.Join(commits, " "))
+	}
+
+	data, _ := json.Marshal(t) // can't fail for these types
+	fmt.Fprintf(&b, "\n%s\n<!-- auto-claude:timeline %s -->\n", statusCommentMarker, data)
+	return b.String()
+}
+
+// tableCell keeps text on one Markdown table row.
+func tableCell(s string) string {
+	s = strings.ReplaceAll(s, "\r", "")
+	s = strings.ReplaceAll(s, "\n", " ")
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -2,12 +2,12 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
-	"github.com/marcin-skalski/auto-claude/internal/claude"
 	"github.com/marcin-skalski/auto-claude/internal/config"
-	"github.com/marcin-skalski/auto-claude/internal/git"
 	"github.com/marcin-skalski/auto-claude/internal/github"
 )
 
@@ -20,44 +20,45 @@ const (
 	stateReviewsPending
 	stateChecksPending
 	stateReady
-)
-
-var (
-	copilotAuthors = map[string]struct{}{
-		"Copilot":                       {},
-		"copilot":                       {},
-		"github-copilot[bot]":           {},
-		"copilot-pull-request-reviewer": {},
-	}
-
-	renovateAuthors = map[string]struct{}{
-		"renovate":      {},
-		"renovate[bot]": {},
-		"renovate-bot":  {},
-		"app/renovate":  {},
-	}
+	stateMergeQueued
+	stateAutoMergePending
+	stateDequeued
+	stateChecksRerun
+	stateChecksBlocked
+	stateBotReviewPending
+	stateAwaitingHuman
 )
 
 type Worker struct {
-	repo   config.RepoConfig
-	pr     github.PRInfo
-	gh     *github.Client
-	claude *claude.Client
-	git    *git.Client
-	logger *slog.Logger
-
-	cachedReviews       []github.Review
-	cachedReviewThreads []github.ReviewThread
+	repo      config.RepoConfig
+	pr        github.PRInfo
+	required  github.RequiredChecks // for the PR's base
+	reviewers []string              // logins whose review feedback Claude addresses, see HumanReviewsConfig
+	memory    *Memory
+	jobLogs   map[int64]string // key: job ID, downloaded this run
+	gh        GitHub
+	claude    Agent
+	git       Git
+	logger    *slog.Logger
+
+	claudeAction string   // of this worker's latest Claude run
+	actionCost   float64  // of this worker's Claude runs
+	pushed       []string // commits this worker pushed
 
 	onClaudeStart  func(action string)
 	onClaudeEnd    func()
 	onClaudeOutput func(line string)
 }
 
-func New(repo config.RepoConfig, pr github.PRInfo, gh *github.Client, cl *claude.Client, g *git.Client, logger *slog.Logger, onClaudeStart func(action string), onClaudeEnd func(), onClaudeOutput func(line string)) *Worker {
+func New(repo config.RepoConfig, pr github.PRInfo, required github.RequiredChecks, reviewers []string, memory *Memory, gh GitHub, cl Agent, g Git, logger *slog.Logger, onClaudeStart func(action string), onClaudeEnd func(), onClaudeOutput func(line string)) *Worker {
+	github.MarkRequired(pr.Checks, required)
 	return &Worker{
 		repo:           repo,
 		pr:             pr,
+		required:       required,
+		reviewers:      reviewers,
+		memory:         memory,
+		jobLogs:        make(map[int64]string),
 		gh:             gh,
 		claude:         cl,
 		git:            g,
@@ -73,21 +74,22 @@ func (w *Worker) Run(ctx context.Context) error {
 	w.logger.Info("worker started", "title", w.pr.Title, "head", w.pr.HeadRef)
 
 	// Setup worktree
-	if err := w.git.EnsureClone(ctx, w.repo.Owner, w.repo.Name); err != nil {
+	if err := w.git.EnsureClone(ctx, w.repo.Host, w.repo.Owner, w.repo.Name); err != nil {
 		return fmt.Errorf("ensure clone: %w", err)
 	}
 
-	wtDir, err := w.git.AddWorktree(ctx, w.repo.Owner, w.repo.Name, w.pr.HeadRef, w.pr.Number)
+	wtDir, err := w.git.AddWorktree(ctx, w.repo.Host, w.repo.Owner, w.repo.Name, w.pr.HeadRef, w.pr.Number)
 	if err != nil {
 		return fmt.Errorf("add worktree: %w", err)
 	}
 	defer func() {
-		if err := w.git.RemoveWorktree(context.Background(), w.repo.Owner, w.repo.Name, w.pr.Number); err != nil {
+		if err := w.git.RemoveWorktree(context.Background(), w.repo.Host, w.repo.Owner, w.repo.Name, w.pr.Number); err != nil {
 			w.logger.Error("failed to remove worktree", "error", err)
 		}
 	}()
 
 	consecutiveFailures := 0
+	refresh := false
 
 	for {
 		select {
@@ -97,74 +99,72 @@ func (w *Worker) Run(ctx context.Context) error {
 		default:
 		}
 
-		// Refresh PR state
-		pr, err := w.gh.GetPRDetail(ctx, w.repo.Owner, w.repo.Name, w.pr.Number)
-		if err != nil {
-			w.logger.Error("failed to get PR detail", "err", err)
-			consecutiveFailures++
-			w.sleep(ctx, consecutiveFailures)
-			continue
-		}
-		w.pr = *pr
-
-		// Fetch reviews and review threads for Copilot review status (only if required and not Renovate)
-		requireCopilot := w.repo.RequireCopilotReview != nil && *w.repo.RequireCopilotReview
-		isRenovate := isRenovateAuthor(w.pr.Author.Login)
-
-		w.logger.Debug("copilot review check",
-			"require_copilot_review", requireCopilot,
-			"is_renovate", isRenovate,
-			"author", w.pr.Author.Login,
-			"config_ptr_nil", w.repo.RequireCopilotReview == nil)
-
-		if requireCopilot && !isRenovate {
-			reviews, err := w.gh.GetReviews(ctx, w.repo.Owner, w.repo.Name, w.pr.Number)
+		// The first pass uses the PR as fetched by the daemon's poll, which
+		// already carries reviews and review threads. Later passes refresh it.
+		if refresh {
+			if limit := w.gh.RateLimit(); limit.Low() {
+				w.logger.Warn("API quota low, deferring to next poll",
+					"resource", limit.Resource,
+					"remaining", limit.Remaining,
+					"reset", limit.Reset)
+				return nil
+			}
+			pr, err := w.gh.GetPRDetail(ctx, w.repo.Owner, w.repo.Name, w.pr.Number)
+			var notFound *github.NotFoundError
+			var rateLimited *github.RateLimitError
+			var authErr *github.AuthError
+			switch {
+			case errors.As(err, &notFound):
+				w.logger.Info("PR no longer exists, exiting worker")
+				return nil
+			case errors.As(err, &rateLimited):
+				w.logger.Warn("rate limited, deferring to next poll", "reset", rateLimited.Reset)
+				return nil
+			case errors.As(err, &authErr):
+				return fmt.Errorf("get PR detail: %w", err)
+			}
 			if err != nil {
-				w.logger.Error("failed to get reviews", "err", err)
+				w.logger.Error("failed to get PR detail", "err", err)
 				consecutiveFailures++
 				w.sleep(ctx, consecutiveFailures)
 				continue
 			}
-			w.cachedReviews = reviews
-
-			// Only fetch review threads if Copilot review exists (ignore PENDING/DISMISSED)
-			hasCopilotReview := false
-			for _, r := range reviews {
-				if isCopilotAuthor(r.Author) && r.State != "PENDING" && r.State != "DISMISSED" {
-					hasCopilotReview = true
-					break
-				}
-			}
-
-			if hasCopilotReview {
-				threads, err := w.gh.GetReviewThreads(ctx, w.repo.Owner, w.repo.Name, w.pr.Number)
-				if err != nil {
-					w.logger.Error("failed to get review threads", "err", err)
-					consecutiveFailures++
-					w.sleep(ctx, consecutiveFailures)
-					continue
-				}
-				w.cachedReviewThreads = threads
-			} else {
-				w.cachedReviewThreads = nil
-			}
-		} else {
-			// Reviews not required or Renovate PR - clear cached reviews
-			w.cachedReviews = nil
-			w.cachedReviewThreads = nil
-			if !isRenovate && !requireCopilot {
-				w.logger.Warn("copilot review check skipped unexpectedly",
-					"require_copilot_review", requireCopilot,
-					"config_ptr", w.repo.RequireCopilotReview)
-			}
+			w.pr = *pr
+			github.MarkRequired(w.pr.Checks, w.required)
 		}
+		refresh = true
 
-		// Reset counter after successful PR and thread fetch
+		// Reset counter after successful PR fetch
 		consecutiveFailures = 0
 
+		// Actions requested with PR comment commands go first
+		started := time.Now()
+		if done, err := w.runRequests(ctx, wtDir); done {
+			if err != nil {
+				w.logger.Error("requested action failed", "err", err)
+			}
+			w.recordAction("requested_fix", err)
+			w.noteAction(ctx, "requested", "requested_fix", started, err)
+			return nil
+		}
+
 		s := w.evaluate()
 		w.logger.Info("evaluated state", "state", stateString(s))
+		if isFixState(s) || s == stateReady {
+			w.memory.enterState(stateString(s))
+		}
+		w.publishStatus(ctx, s)
+		if reason := w.waitReason(s); reason != "" {
+			w.noteWait(ctx, s, reason)
+		}
+		if w.outOfAttempts(s) {
+			w.logger.Warn("giving up after repeated fix attempts, waiting for a human",
+				"state", stateString(s),
+				"attempts", w.memory.Attempts())
+			return nil
+		}
 
+		started = time.Now()
 		var actionErr error
 		switch s {
 		case stateDraft:
@@ -175,49 +175,83 @@ func (w *Worker) Run(ctx context.Context) error {
 			actionErr = w.resolveConflicts(ctx, wtDir)
 
 		case stateChecksFailing:
-			actionErr = w.fixChecks(ctx, wtDir)
+			actionErr = w.handleFailingChecks(ctx, wtDir)
 
 		case stateReviewsPending:
-			// Check if we have Copilot reviews to fix
-			hasUnresolvedCopilot := false
-			for _, t := range w.cachedReviewThreads {
-				if t.IsResolved || t.IsOutdated {
-					continue
-				}
-				for _, c := range t.Comments {
-					if isCopilotAuthor(c.Author) {
-						hasUnresolvedCopilot = true
-						break
-					}
-				}
-				if hasUnresolvedCopilot {
-					break
-				}
+			if threads := w.autoFixThreads(); len(threads) > 0 {
+				actionErr = w.fixReviews(ctx, wtDir, threads)
+				break
 			}
-
-			if hasUnresolvedCopilot {
-				actionErr = w.fixReviews(ctx, wtDir)
-			} else {
-				// No Copilot reviews, just waiting for human reviews
-				actionErr = w.requestReview(ctx)
+			addressed, err := w.addressHumanReviews(ctx, wtDir)
+			if err != nil || addressed {
+				actionErr = err
+				break
 			}
+			// Nothing for Claude to address, just waiting for human reviews
+			actionErr = w.requestReview(ctx)
 
 		case stateChecksPending:
 			w.logger.Info("checks pending, waiting for next poll")
 			return nil
 
+		case stateBotReviewPending:
+			actionErr = w.requestBotReviews(ctx)
+
+		case stateChecksRerun:
+			actionErr = w.rerunChecks(ctx)
+
+		case stateChecksBlocked:
+			w.logger.Info("checks need attention from a human, waiting for next poll")
+			return nil
+
+		case stateAwaitingHuman:
+			w.logger.Info("review threads wait for a human, waiting for next poll")
+			return nil
+
+		case stateMergeQueued:
+			w.logger.Info("PR in merge queue, waiting for next poll",
+				"position", w.pr.MergeQueueEntry.Position,
+				"queue_state", w.pr.MergeQueueEntry.State)
+			return nil
+
+		case stateAutoMergePending:
+			// Auto-merge waits for an up-to-date branch but never updates it
+			if w.pr.MergeStateStatus == "BEHIND" {
+				actionErr = w.gh.UpdateBranch(ctx, w.repo.Owner, w.repo.Name, w.pr.Number)
+				break
+			}
+			w.logger.Info("auto-merge enabled, waiting for GitHub to merge")
+			return nil
+
+		case stateDequeued:
+			w.logger.Warn("PR was removed from the merge queue, waiting for a new push",
+				"reason", w.pr.Dequeued.Reason,
+				"at", w.pr.Dequeued.At)
+			return nil
+
 		case stateReady:
 			w.logger.Info("PR ready to merge")
-			if err := w.merge(ctx); err != nil {
-				w.logger.Error("merge failed", "err", err)
-				consecutiveFailures++
-				w.sleep(ctx, consecutiveFailures)
-				continue
+			if err := w.merge(ctx, wtDir); err != nil {
+				var authErr *github.AuthError
+				if errors.As(err, &authErr) {
+					return fmt.Errorf("merge: %w", err)
+				}
+				w.logger.Error("merge failed, will retry on next poll", "err", err)
+				w.recordAction("merging", err)
+				w.noteAction(ctx, stateString(s), "merging", started, err)
+				return nil
 			}
-			w.logger.Info("PR merged successfully")
+			w.noteAction(ctx, stateString(s), "merging", started, nil)
 			return nil
 		}
 
+		if w.claudeAction != "" && isFixState(s) {
+			w.memory.addAttempt()
+		}
+		w.recordAction(actionName(s), actionErr)
+		w.publishStatus(ctx, s)
+		w.noteAction(ctx, stateString(s), actionName(s), started, actionErr)
+
 		if actionErr != nil {
 			w.logger.Error("action failed, will retry on next poll", "state", stateString(s), "err", actionErr)
 			return nil
@@ -233,58 +267,47 @@ func (w *Worker) evaluate() state {
 		return stateDraft
 	}
 
+	// The queue owns queued PRs; it removes them if they stop passing
+	if w.pr.MergeQueueEntry != nil {
+		return stateMergeQueued
+	}
+
 	if w.pr.Mergeable == "CONFLICTING" {
 		return stateConflicting
 	}
 
-	// Check for failing checks
-	for _, c := range w.pr.Checks {
-		if c.Conclusion == "failure" {
-			return stateChecksFailing
+	// Failing checks first: fixing them re-runs everything anyway
+	pending := false
+	actions := make(map[string]bool) // key: config.Check* action
+	for _, c := range w.gatingChecks() {
+		if c.Completed() {
+			actions[w.checkAction(c)] = true
+		} else {
+			pending = true
 		}
 	}
-
-	// Check for pending checks
-	for _, c := range w.pr.Checks {
-		if c.Conclusion == "" && c.Status != "COMPLETED" {
-			return stateChecksPending
-		}
+	switch {
+	case actions[config.CheckFailing]:
+		return stateChecksFailing
+	case actions[config.CheckRerun]:
+		return stateChecksRerun
+	case pending:
+		return stateChecksPending
+	case actions[config.CheckBlock]:
+		return stateChecksBlocked
 	}
 
-	// Check review status before merging (if required and not Renovate)
-	requireCopilot := w.repo.RequireCopilotReview != nil && *w.repo.RequireCopilotReview
-	isRenovate := isRenovateAuthor(w.pr.Author.Login)
-
-	w.logger.Debug("evaluate copilot requirement",
-		"require_copilot_review", requireCopilot,
-		"is_renovate", isRenovate,
-		"author", w.pr.Author.Login,
-		"cached_reviews_count", len(w.cachedReviews),
-		"cached_threads_count", len(w.cachedReviewThreads))
-
-	if requireCopilot && !isRenovate {
-		// Safety check: ensure reviews were fetched
-		if w.cachedReviews == nil {
-			w.logger.Error("copilot review required but reviews not fetched - blocking merge",
-				"pr", w.pr.Number,
-				"author", w.pr.Author.Login)
-			return stateChecksPending
-		}
+	// Required bot reviews before merging (Renovate is exempt)
+	if s, ok := w.evaluateBotReviews(); ok {
+		return s
+	}
 
-		copilotStatus := w.checkCopilotReviewStatus()
-		w.logger.Debug("copilot review status", "status", copilotStatus)
-		switch copilotStatus {
-		case copilotNotReviewed:
-			w.logger.Info("waiting for Copilot review to complete")
-			return stateChecksPending
-		case copilotUnresolved:
-			return stateReviewsPending
-		case copilotResolved:
-			// Continue to merge readiness check
-		}
+	// Feedback of allowlisted reviewers waiting for an answer
+	if len(w.humanThreads()) > 0 {
+		return stateReviewsPending
 	}
 
-	// Check branch protection approval (separate from Copilot review)
+	// Check branch protection approval (separate from bot reviews)
 	// Uses ReviewDecision (branch protection) not MergeStateStatus (general blocking)
 	// because ReviewDecision specifically reflects required approval rules
 	if w.pr.ReviewDecision != "" && w.pr.ReviewDecision != "APPROVED" {
@@ -302,7 +325,7 @@ func (w *Worker) evaluate() state {
 	if w.pr.MergeStateStatus == "BEHIND" {
 		w.logger.Debug("PR behind base branch, will attempt merge to trigger update",
 			"mergeStateStatus", w.pr.MergeStateStatus)
-		return stateReady
+		return w.readyState()
 	}
 
 	// BLOCKED means branch protection requires approval
@@ -312,70 +335,70 @@ func (w *Worker) evaluate() state {
 		return stateReviewsPending
 	}
 
-	if w.pr.MergeStateStatus != "CLEAN" {
+	// UNSTABLE means only optional checks are failing or pending
+	switch w.pr.MergeStateStatus {
+	case "CLEAN", "UNSTABLE", "HAS_HOOKS":
+	default:
 		w.logger.Debug("PR merge state not clean",
 			"mergeStateStatus", w.pr.MergeStateStatus,
 			"mergeable", w.pr.Mergeable)
+		if w.pr.AutoMergeEnabled {
+			return stateAutoMergePending
+		}
 		return stateChecksPending
 	}
 
-	return stateReady
+	return w.readyState()
 }
 
-type copilotReviewStatus int
-
-const (
-	copilotNotReviewed copilotReviewStatus = iota
-	copilotUnresolved
-	copilotResolved
-)
-
-func (w *Worker) checkCopilotReviewStatus() copilotReviewStatus {
-	var hasCopilotReview bool
-	var hasUnresolvedComment bool
-
-	// Check top-level reviews for any non-dismissed Copilot review
-	for _, r := range w.cachedReviews {
-		if isCopilotAuthor(r.Author) {
-			// Only consider submitted reviews (ignore PENDING/DISMISSED)
-			if r.State != "PENDING" && r.State != "DISMISSED" {
-				hasCopilotReview = true
-			}
-		}
+// readyState refines stateReady for PRs already handed to GitHub's auto-merge
+// or dropped from the merge queue. A dequeued PR isn't re-queued until a push
+// (or re-queue by someone else) supersedes the removal.
+func (w *Worker) readyState() state {
+	switch {
+	case w.pr.AutoMergeEnabled:
+		return stateAutoMergePending
+	case w.pr.Dequeued != nil:
+		return stateDequeued
+	default:
+		return stateReady
 	}
+}
 
-	// Check review threads (inline comments)
-	for _, t := range w.cachedReviewThreads {
-		for _, c := range t.Comments {
-			if isCopilotAuthor(c.Author) {
-				hasCopilotReview = true
-				if !t.IsResolved && !t.IsOutdated {
-					hasUnresolvedComment = true
-				}
-				break
-			}
+// gatingChecks returns the checks that decide whether the PR gets fixed or
+// merged: the required ones, or all of them with fix_optional_checks.
+// auto-claude's own status check never counts.
+func (w *Worker) gatingChecks() []github.Check {
+	var checks []github.Check
+	for _, c := range w.pr.Checks {
+		if (c.Required || w.repo.FixOptionalChecks) && !w.repo.IsStatusCheck(c.Name) {
+			checks = append(checks, c)
 		}
 	}
-
-	if !hasCopilotReview {
-		return copilotNotReviewed
-	}
-	// Copilot typically provides COMMENTED state (not APPROVED)
-	// Only check for unresolved threads; approval enforced via reviewDecision
-	if hasUnresolvedComment {
-		return copilotUnresolved
-	}
-	return copilotResolved
+	return checks
 }
 
-func isCopilotAuthor(author string) bool {
-	_, ok := copilotAuthors[author]
-	return ok
+// CheckAction is the configured action for a completed check, see
+// config.RepoConfig.CheckAction. Commit statuses can't be re-run, so they're
+// fixed instead, and failures whose remembered category is configured as
+// ignore count as passed. memory may be nil.
+func CheckAction(repo config.RepoConfig, memory *Memory, headSHA string, c github.Check) string {
+	action := repo.CheckAction(c.Conclusion)
+	switch action {
+	case config.CheckRerun:
+		if c.ID == 0 {
+			return config.CheckFailing
+		}
+	case config.CheckFailing:
+		if category := memory.Category(headSHA, c); category != "" && repo.FailureAction(category) == config.FailureIgnore {
+			return config.CheckIgnore
+		}
+	}
+	return action
 }
 
-func isRenovateAuthor(author string) bool {
-	_, ok := renovateAuthors[author]
-	return ok
+func (w *Worker) checkAction(c github.Check) string {
+	return CheckAction(w.repo, w.memory, w.pr.HeadSHA, c)
 }
 
 func stateString(s state) string {
@@ -392,6 +415,20 @@ func stateString(s state) string {
 		return "checks_pending"
 	case stateReady:
 		return "ready"
+	case stateMergeQueued:
+		return "merge_queued"
+	case stateAutoMergePending:
+		return "auto_merge_pending"
+	case stateDequeued:
+		return "dequeued"
+	case stateChecksRerun:
+		return "checks_rerun"
+	case stateChecksBlocked:
+		return "checks_blocked"
+	case stateBotReviewPending:
+		return "bot_review_pending"
+	case stateAwaitingHuman:
+		return "awaiting_human"
 	default:
 		return "unknown"
 	}
@@ -0,0 +1,346 @@
+package worker_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/marcin-skalski/auto-claude/internal/claude"
+	"github.com/marcin-skalski/auto-claude/internal/config"
+	"github.com/marcin-skalski/auto-claude/internal/fake"
+	"github.com/marcin-skalski/auto-claude/internal/github"
+	"github.com/marcin-skalski/auto-claude/internal/worker"
+)
+
+// loadRepo loads a config with one repo o/r, with settings appended to its
+// YAML, and returns the repo with defaults applied.
+func loadRepo(t *testing.T, settings string) config.RepoConfig {
+	t.Helper()
+	yaml := "repos:\n  - owner: o\n    name: r\n    require_copilot_review: false\n"
+	for _, line := range strings.Split(strings.TrimSpace(settings), "\n") {
+		if line != "" {
+			yaml += "    " + line + "\n"
+		}
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg.Repos[0]
+}
+
+// readyPR returns a PR with nothing left to do but merging.
+func readyPR() github.PRInfo {
+	return github.PRInfo{
+		Number:           1,
+		Title:            "Add feature",
+		HeadRef:          "feature",
+		HeadSHA:          "abc",
+		BaseRef:          "main",
+		Author:           github.Author{Login: "dev"},
+		Mergeable:        "MERGEABLE",
+		MergeStateStatus: "CLEAN",
+		Checks: []github.Check{
+			{Name: "test", Status: "COMPLETED", Conclusion: github.ConclusionSuccess, ID: 7, App: "github-actions"},
+		},
+	}
+}
+
+const botSettings = `
+review_bots:
+  - name: coderabbit
+    logins: ["coderabbitai[bot]"]
+    required: true
+    auto_fix: true`
+
+func botThread(comments ...string) github.ReviewThread {
+	t := github.ReviewThread{ID: "T1", Path: "main.go", Line: 3}
+	for i, body := range comments {
+		author := "coderabbitai[bot]"
+		if i%2 == 1 {
+			author = "auto-claude"
+		}
+		t.Comments = append(t.Comments, github.ReviewComment{Author: author, Body: body})
+	}
+	return t
+}
+
+func TestWorkerRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		pr       func(pr *github.PRInfo)
+		required github.RequiredChecks
+		reviews  []github.Review
+		threads  []github.ReviewThread
+		jobLog   string
+		output   string // Claude's reply
+		commits  bool   // whether Claude commits
+
+		wantPrompt   string // in the only Claude prompt; empty if Claude mustn't run
+		wantPushes   int
+		wantMerges   int
+		wantReruns   []string
+		wantResolved []string
+		wantRequests []string // bot review requests
+		wantReply    string   // in the last comment on thread T1
+	}{
+		{
+			name:       "conflict is resolved and pushed",
+			pr:         func(pr *github.PRInfo) { pr.Mergeable = "CONFLICTING" },
+			commits:    true,
+			wantPrompt: "conflicts with main",
+			wantPushes: 1,
+		},
+		{
+			name:       "conflict without commits pushes nothing",
+			pr:         func(pr *github.PRInfo) { pr.Mergeable = "CONFLICTING" },
+			wantPrompt: "conflicts with main",
+		},
+		{
+			name: "failed job is re-run before Claude fixes it",
+			pr: func(pr *github.PRInfo) {
+				pr.Checks[0].Conclusion = github.ConclusionFailure
+			},
+			jobLog:     "--- FAIL: TestX (0.00s)\nFAIL\n",
+			wantReruns: []string{"test"},
+		},
+		{
+			name:     "test failure is fixed",
+			settings: "flaky: {max_reruns: 0}",
+			pr: func(pr *github.PRInfo) {
+				pr.Checks[0].Conclusion = github.ConclusionFailure
+			},
+			jobLog:     "--- FAIL: TestX (0.00s)\nFAIL\n",
+			commits:    true,
+			wantPrompt: "CI checks failing",
+			wantPushes: 1,
+		},
+		{
+			name:     "infrastructure failure is re-run, not fixed",
+			settings: "flaky: {max_reruns: 0}",
+			pr: func(pr *github.PRInfo) {
+				pr.Checks[0].Conclusion = github.ConclusionFailure
+			},
+			jobLog:     "##[error]The operation was canceled.\n",
+			wantReruns: []string{"test"},
+		},
+		{
+			name:         "bot review thread is fixed, answered and resolved",
+			settings:     botSettings,
+			threads:      []github.ReviewThread{botThread("Rename x.")},
+			output:       "Done.\n```json\n{\"threads\": [{\"id\": \"T1\", \"outcome\": \"fixed\", \"rationale\": \"Renamed it.\"}]}\n```",
+			commits:      true,
+			wantPrompt:   "Review bots left the comments below",
+			wantPushes:   1,
+			wantResolved: []string{"T1"},
+			wantReply:    "**fixed**: Renamed it.",
+		},
+		{
+			name:       "questionable bot review thread stays open",
+			settings:   botSettings,
+			threads:    []github.ReviewThread{botThread("Use a map?")},
+			output:     "```json\n{\"threads\": [{\"id\": \"T1\", \"outcome\": \"questionable\", \"rationale\": \"Depends on the sizes.\"}]}\n```",
+			wantPrompt: "Review bots left the comments below",
+			wantReply:  "**questionable**: Depends on the sizes.",
+		},
+		{
+			name:     "thread answered by auto-claude waits for a human",
+			settings: botSettings,
+			threads:  []github.ReviewThread{botThread("Use a map?", "**questionable**: Depends.\n\n<!-- auto-claude:reply -->")},
+		},
+		{
+			name:       "unreadable review outcome still answers with the commits",
+			settings:   botSettings,
+			threads:    []github.ReviewThread{botThread("Rename x.")},
+			output:     "I renamed it.",
+			commits:    true,
+			wantPrompt: "Review bots left the comments below",
+			wantPushes: 1,
+			wantReply:  "Claude pushed 0000000",
+		},
+		{
+			name:         "missing bot review is requested",
+			settings:     botSettings + "\n    request: coderabbitai",
+			wantRequests: []string{"o/r#1 copilot-pull-request-reviewer", "o/r#1 coderabbitai"},
+		},
+		{
+			name:     "bot review of an earlier commit counts after a push",
+			settings: botSettings,
+			pr:       func(pr *github.PRInfo) { pr.HeadSHA = "def" },
+			reviews: []github.Review{
+				{ID: "R1", Author: "coderabbitai[bot]", State: "COMMENTED", CommitSHA: "abc"},
+			},
+			threads:    []github.ReviewThread{{ID: "T1", IsResolved: true, Comments: []github.ReviewComment{{Author: "coderabbitai[bot]", Body: "Rename x."}}}},
+			wantMerges: 1,
+		},
+		{
+			name:       "ready PR is merged",
+			wantMerges: 1,
+		},
+		{
+			name:     "failing check no rule requires doesn't gate",
+			required: github.RequiredChecks{Known: true},
+			pr: func(pr *github.PRInfo) {
+				pr.Checks[0].Conclusion = github.ConclusionFailure
+			},
+			wantMerges: 1,
+		},
+		{
+			name:     "failing check gates when required checks are unknown",
+			settings: "flaky: {max_reruns: 0}",
+			pr: func(pr *github.PRInfo) {
+				pr.Checks[0].Conclusion = github.ConclusionFailure
+			},
+			jobLog:     "--- FAIL: TestX (0.00s)\nFAIL\n",
+			wantPrompt: "CI checks failing",
+		},
+		{
+			name: "draft is left alone",
+			pr:   func(pr *github.PRInfo) { pr.IsDraft = true },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := loadRepo(t, tt.settings)
+			pr := readyPR()
+			if tt.pr != nil {
+				tt.pr(&pr)
+			}
+
+			gh := fake.NewGitHub()
+			gh.AddPR("o", "r", pr)
+			gh.SetReviews("o", "r", 1, tt.reviews)
+			gh.SetReviewThreads("o", "r", 1, tt.threads)
+			gh.SetJobLogs(7, tt.jobLog)
+			g := fake.NewGit(t.TempDir())
+			agent := fake.NewAgent()
+			agent.Result = &claude.Result{Success: true, Output: tt.output}
+			agent.OnRun = func(call fake.AgentCall) {
+				if tt.commits {
+					g.Commit(call.Workdir)
+				}
+			}
+
+			listed, err := gh.ListOpenPRs(context.Background(), "o", "r", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			w := worker.New(repo, listed[0], tt.required, nil, worker.NewMemory(), gh, agent, g, logger, func(string) {}, func() {}, func(string) {})
+			if err := w.Run(context.Background()); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+
+			calls := agent.Calls()
+			switch {
+			case tt.wantPrompt == "" && len(calls) > 0:
+				t.Errorf("Claude ran with %q, want no run", calls[0].Prompt)
+			case tt.wantPrompt != "" && len(calls) != 1:
+				t.Errorf("Claude ran %d times, want once", len(calls))
+			case tt.wantPrompt != "" && !strings.Contains(calls[0].Prompt, tt.wantPrompt):
+				t.Errorf("prompt = %q, want it to contain %q", calls[0].Prompt, tt.wantPrompt)
+			}
+			if got := len(g.Pushes()); got != tt.wantPushes {
+				t.Errorf("pushes = %d, want %d", got, tt.wantPushes)
+			}
+			if got := len(gh.Merges()); got != tt.wantMerges {
+				t.Errorf("merges = %d, want %d", got, tt.wantMerges)
+			}
+			if got := gh.Reruns(); !slices.Equal(got, tt.wantReruns) {
+				t.Errorf("reruns = %v, want %v", got, tt.wantReruns)
+			}
+			if got := gh.ResolvedThreads(); !slices.Equal(got, tt.wantResolved) {
+				t.Errorf("resolved threads = %v, want %v", got, tt.wantResolved)
+			}
+			if got := gh.ReviewRequests(); !slices.Equal(got, tt.wantRequests) {
+				t.Errorf("review requests = %v, want %v", got, tt.wantRequests)
+			}
+			if tt.wantReply != "" {
+				threads, err := gh.GetReviewThreads(context.Background(), "o", "r", 1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				last := threads[0].Comments[len(threads[0].Comments)-1]
+				if last.Author != "auto-claude" || !strings.Contains(last.Body, tt.wantReply) {
+					t.Errorf("last thread comment = %s: %q, want a reply containing %q", last.Author, last.Body, tt.wantReply)
+				}
+			}
+		})
+	}
+}
+
+func TestFailingMergeSummarizesOnce(t *testing.T) {
+	repo := loadRepo(t, "merge_method: squash\ncommit_message: {summarize: true, body: \"{{.Summary}}\"}")
+	gh := fake.NewGitHub()
+	gh.AddPR("o", "r", readyPR())
+	gh.SetError("MergePR", &github.TransientError{APIError: github.APIError{StatusCode: 502, Message: "bad gateway"}})
+	g := fake.NewGit(t.TempDir())
+	agent := fake.NewAgent()
+	agent.Result = &claude.Result{Success: true, Output: "- Add feature"}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	memory := worker.NewMemory()
+
+	// Each poll starts a worker that tries to merge once
+	for range 3 {
+		listed, err := gh.ListOpenPRs(context.Background(), "o", "r", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := worker.New(repo, listed[0], github.RequiredChecks{}, nil, memory, gh, agent, g, logger, func(string) {}, func() {}, func(string) {})
+		if err := w.Run(context.Background()); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	}
+	if got := len(agent.Calls()); got != 1 {
+		t.Errorf("Claude ran %d times, want once", got)
+	}
+	if got := len(gh.Merges()); got != 0 {
+		t.Errorf("merges = %d, want 0", got)
+	}
+}
+
+func TestStatusComment(t *testing.T) {
+	repo := loadRepo(t, "status_comment: {enabled: true}\nflaky: {max_reruns: 0}")
+	pr := readyPR()
+	pr.Checks[0].Conclusion = github.ConclusionFailure
+	quote := "> ### auto-claude status\n> <!-- auto-claude:status -->\n\nWhy did it stop?"
+	pr.Comments = []github.Comment{{ID: "human", Author: "dev", Body: quote}}
+	gh := fake.NewGitHub()
+	gh.AddPR("o", "r", pr)
+	gh.SetJobLogs(7, "--- FAIL: TestX (0.00s)\nFAIL\n")
+	g := fake.NewGit(t.TempDir())
+	agent := fake.NewAgent()
+	agent.Result = &claude.Result{Output: "Tool use denied\n" + strings.Repeat("transcript ", 1000)}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	listed, err := gh.ListOpenPRs(context.Background(), "o", "r", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := worker.New(repo, listed[0], github.RequiredChecks{}, nil, worker.NewMemory(), gh, agent, g, logger, func(string) {}, func() {}, func(string) {})
+	if err := w.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	comments := gh.Comments("o", "r", 1)
+	if len(comments) != 2 || comments[0] != quote {
+		t.Fatalf("comments = %q, want the quote untouched and a status comment", comments)
+	}
+	status := comments[1]
+	if !strings.Contains(status, "failed: claude failed: Tool use denied") {
+		t.Errorf("status comment doesn't show the failure:\n%s", status)
+	}
+	if strings.Contains(status, "transcript") {
+		t.Errorf("status comment publishes Claude's output:\n%s", status)
+	}
+}